@@ -0,0 +1,476 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This file looks inside Go's allocator - size classes, spans and their limits
+
+func init() {
+	Register(newDemo("size-classes", "Which allocator size classes the heap demos land in", DemonstrateSizeClasses).in(categoryAllocator))
+	Register(newDemo("size-class-explorer", "Every size from 1 byte to 32KB: its size class and the rounding waste", DemonstrateSizeClassExplorer).in(categoryAllocator))
+	Register(newDemo("tiny-allocator", "Thousands of 4-15 byte pointer-free values packed into 16-byte blocks", DemonstrateTinyAllocator).in(categoryAllocator))
+	Register(newDemo("large-objects", "Allocations just under and just over the 32KB large-object threshold", DemonstrateLargeObjects).in(categoryAllocator))
+	Register(newDemo("zeroing-cost", "Cost of make zeroing 64MB vs reusing a pooled buffer", DemonstrateZeroingCost).in(categoryAllocator))
+	Register(newDemo("pool-gc-clearing", "sync.Pool survives one GC, not two: counting New calls", DemonstratePoolGCClearing).in(categoryAllocator))
+	Register(newDemo("pool-hit-rate", "Fresh 4KB buffers vs sync.Pool reuse under concurrent load", DemonstratePoolHitRate).in(categoryAllocator))
+}
+
+// sizeClassDelta is the change in one BySize entry across a measurement
+type sizeClassDelta struct {
+	size    uint32
+	mallocs uint64
+	frees   uint64
+}
+
+// diffBySize returns the size classes whose malloc or free counts changed
+func diffBySize(before, after *runtime.MemStats) []sizeClassDelta {
+	var deltas []sizeClassDelta
+	for i := range after.BySize {
+		mallocs := after.BySize[i].Mallocs - before.BySize[i].Mallocs
+		frees := after.BySize[i].Frees - before.BySize[i].Frees
+		if mallocs != 0 || frees != 0 {
+			deltas = append(deltas, sizeClassDelta{size: after.BySize[i].Size, mallocs: mallocs, frees: frees})
+		}
+	}
+	return deltas
+}
+
+// Demonstrate the size-class histogram of the heap allocation examples
+func DemonstrateSizeClasses() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ALLOCATION SIZE CLASSES (MemStats.BySize)")
+	fmt.Fprintln(out, "============================================================")
+
+	// Stays on ReadMemStats whatever -stats says: BySize only exists there,
+	// and only its stop-the-world flush makes a delta of 20 small
+	// allocations exact - runtime/metrics would credit whole spans
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	heapAllocationViaPointer()
+	runtime.ReadMemStats(&after)
+
+	fmt.Fprintln(out, "  heapAllocationViaPointer: 10 x createLargeObject")
+	fmt.Fprintf(out, "  %13s %10s %8s\n", "Class (bytes)", "Mallocs", "Frees")
+	for _, d := range diffBySize(&before, &after) {
+		fmt.Fprintf(out, "  %13d %10d %8d\n", d.size, d.mallocs, d.frees)
+	}
+
+	fmt.Fprintln(out, "\n  Small objects (<= 32KB) are rounded up to one of ~68 size classes, each")
+	fmt.Fprintln(out, "  served from its own spans - no per-object headers, little fragmentation.")
+	fmt.Fprintln(out, "  The 32-byte LargeObject structs land in the 32 class and their 1024-byte")
+	fmt.Fprintln(out, "  Data arrays in the 1024 class (the []*LargeObject itself stays on the stack).")
+}
+
+// The histogram of allocations by size class. Its bucket boundaries are the
+// size classes plus one, so it also describes the allocator's class table.
+const metricAllocsBySize = "/gc/heap/allocs-by-size:bytes"
+
+// Every object at most this size comes from a size class
+const maxSmallSize = 32 << 10
+
+// Sink for the explorer's sweep, so every size really is heap allocated
+var sizeClassSink []byte
+
+// sizeClassRow is one size class in the explorer: the requested sizes it
+// serves and what rounding them up to the class wastes
+type sizeClassRow struct {
+	size        uint64
+	smallest    uint64 // smallest request the class serves
+	allocations uint64 // counted by the runtime during the sweep
+	requested   uint64 // bytes the sweep asked for in this class
+}
+
+// allocsBySize reads the size-class histogram, after a GC has flushed
+// every P's cached allocation counts into it
+func allocsBySize() *metrics.Float64Histogram {
+	runtime.GC()
+	sample := []metrics.Sample{{Name: metricAllocsBySize}}
+	metrics.Read(sample)
+	return sample[0].Value.Float64Histogram()
+}
+
+// exploreSizeClasses allocates one object of every size from 1 byte to
+// maxSmallSize and returns the classes they landed in
+func exploreSizeClasses() []sizeClassRow {
+	before := allocsBySize()
+	for n := 1; n <= maxSmallSize; n++ {
+		sizeClassSink = make([]byte, n)
+	}
+	sizeClassSink = nil
+	after := allocsBySize()
+
+	// Bucket i counts sizes in [Buckets[i], Buckets[i+1]): class Buckets[i+1]-1
+	var rows []sizeClassRow
+	for i := 0; i+1 < len(after.Buckets); i++ {
+		lo, hi := after.Buckets[i], after.Buckets[i+1]
+		if hi > maxSmallSize+1 {
+			break
+		}
+		row := sizeClassRow{
+			size:        uint64(hi) - 1,
+			smallest:    max(uint64(lo), 1),
+			allocations: after.Counts[i] - before.Counts[i],
+		}
+		for n := row.smallest; n <= row.size; n++ {
+			row.requested += n
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Demonstrate every size class from 1 byte to 32KB and what it wastes
+func DemonstrateSizeClassExplorer() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "SIZE-CLASS EXPLORER: EVERY SIZE FROM 1 BYTE TO 32KB")
+	fmt.Fprintln(out, "============================================================")
+
+	rows := exploreSizeClasses()
+	fmt.Fprintf(out, "  Allocated make([]byte, n) once for every n in 1..%d\n\n", maxSmallSize)
+	fmt.Fprintf(out, "  %7s  %-13s  %11s  %9s  %10s\n", "Class", "Serves", "Allocations", "Avg waste", "Worst waste")
+	var requested, rounded uint64
+	for _, r := range rows {
+		sizes := r.size - r.smallest + 1
+		avgWaste := 100 * (1 - float64(r.requested)/float64(sizes*r.size))
+		fmt.Fprintf(out, "  %7d  %-13s  %11d  %8.1f%%  %10s\n",
+			r.size, fmt.Sprintf("%d-%d", r.smallest, r.size), r.allocations, avgWaste, formatBytes(r.size-r.smallest))
+		requested += r.requested
+		rounded += sizes * r.size
+	}
+	waste := 100 * (1 - float64(requested)/float64(rounded))
+	fmt.Fprintf(out, "\n  %d classes; over the sweep %s requested took %s (%.1f%% internal fragmentation)\n",
+		len(rows), formatBytes(requested), formatBytes(rounded), waste)
+	observe("size-class sweep internal fragmentation", waste, "percent")
+
+	fmt.Fprintln(out, "\n  Each small request is rounded up to the next class, and every span (one")
+	fmt.Fprintln(out, "  or more 8KB pages) holds objects of a single class, so a span needs no")
+	fmt.Fprintln(out, "  per-object header and any free slot fits any request of that class. The")
+	fmt.Fprintln(out, "  price is the rounding. The classes are spaced roughly in proportion to")
+	fmt.Fprintln(out, "  their size, so past the first few the average costs a few percent")
+	fmt.Fprintln(out, "  wherever a request falls. Allocations lists what the runtime counted:")
+	fmt.Fprintln(out, "  sizes under 16 bytes show up far fewer times, because pointer-free ones")
+	fmt.Fprintln(out, "  share 16-byte tiny blocks (and the runtime's own allocations add a few).")
+	fmt.Fprintln(out, "  Rust's global allocator is the system malloc or jemalloc, both")
+	fmt.Fprintln(out, "  size-class allocators too - but handed the size back at dealloc, where")
+	fmt.Fprintln(out, "  Go's allocator finds it from the span.")
+}
+
+const (
+	zeroingBufferSize = 64 << 20 // 64MB
+	zeroingIterations = 20
+)
+
+// Keeps each buffer reachable so make can't be optimized away
+var zeroingSink []byte
+
+// Reused buffers - Get hands back the old contents, nothing is re-zeroed
+var zeroingPool = sync.Pool{
+	New: func() any { return make([]byte, zeroingBufferSize) },
+}
+
+// Time fn over zeroingIterations and return ns per call
+func timePerOp(fn func()) float64 {
+	start := time.Now()
+	for i := 0; i < zeroingIterations; i++ {
+		fn()
+	}
+	return float64(time.Since(start).Nanoseconds()) / zeroingIterations
+}
+
+// Demonstrate that Go always zeroes new memory, and what that costs at 64MB
+func DemonstrateZeroingCost() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ZEROING COST: make VS POOLED BUFFER")
+	fmt.Fprintln(out, "============================================================")
+
+	makeNs := timePerOp(func() {
+		buf := make([]byte, zeroingBufferSize) // guaranteed all zeros
+		buf[len(buf)-1] = 1
+		zeroingSink = buf
+	})
+	zeroingSink = nil
+
+	zeroingPool.Put(zeroingPool.Get()) // warm the pool outside the timing
+	poolNs := timePerOp(func() {
+		buf := zeroingPool.Get().([]byte) // previous contents, not zeroed
+		buf[len(buf)-1] = 1
+		zeroingPool.Put(buf)
+	})
+
+	fmt.Fprintf(out, "  make([]byte, 64<<20):    %12.0f ns/op\n", makeNs)
+	fmt.Fprintf(out, "  pooled 64MB buffer:      %12.0f ns/op\n", poolNs)
+	fmt.Fprintln(out, "\n  Go guarantees every allocation starts zeroed - there is no way to get")
+	fmt.Fprintln(out, "  uninitialized memory, so a fresh 64MB slice means clearing (or faulting in)")
+	fmt.Fprintln(out, "  64MB of pages. Reusing a buffer you are about to overwrite skips that.")
+	fmt.Fprintln(out, "  Rust defaults to initialized memory too, but MaybeUninit (and")
+	fmt.Fprintln(out, "  Vec::with_capacity + set_len in unsafe code) lets you opt out.")
+	fmt.Fprintln(out, "  (Pooled buffers keep old data: never hand one out without overwriting it.)")
+}
+
+const pooledObjects = 5
+
+// Put n fresh objects, run gcs collections, Get n back and count how many
+// the pool had to build with New
+func poolMissesAfterGC(gcs int) int {
+	misses := 0
+	pool := sync.Pool{
+		New: func() any {
+			misses++
+			return createLargeObject(-1)
+		},
+	}
+
+	for i := 0; i < pooledObjects; i++ {
+		pool.Put(createLargeObject(i))
+	}
+	for i := 0; i < gcs; i++ {
+		runtime.GC()
+	}
+	for i := 0; i < pooledObjects; i++ {
+		_ = pool.Get().(*LargeObject)
+	}
+	return misses
+}
+
+// Demonstrate that the GC empties a sync.Pool - it is not a cache
+func DemonstratePoolGCClearing() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "SYNC.POOL AND THE GC")
+	fmt.Fprintln(out, "============================================================")
+
+	fmt.Fprintf(out, "  Put %d LargeObjects, then Get %d back:\n", pooledObjects, pooledObjects)
+	for gcs := 0; gcs <= 2; gcs++ {
+		fmt.Fprintf(out, "    after %d GC cycle(s): New called %d times\n", gcs, poolMissesAfterGC(gcs))
+	}
+
+	fmt.Fprintln(out, "\n  Each GC moves the pool's contents to a victim cache and drops the old")
+	fmt.Fprintln(out, "  victims, so a pooled object survives one cycle and is gone after two.")
+	fmt.Fprintln(out, "  A sync.Pool only smooths allocation bursts between collections: never")
+	fmt.Fprintln(out, "  park state in it that you expect to get back (connections, caches).")
+	fmt.Fprintln(out, "  (Under -race the pool also drops items at random to flush out such bugs.)")
+}
+
+// The simulated load: poolWorkers goroutines each serving poolRequests
+// requests, every request needing a poolBufferSize scratch buffer
+const (
+	poolWorkers    = 8
+	poolRequests   = 20_000
+	poolBufferSize = 4 << 10
+)
+
+// requestHandler consumes a request's buffer. Calling it through a variable
+// hides it from escape analysis, as an io.Writer would in real code, so the
+// buffers really are heap allocated.
+var requestHandler = func(buf []byte) int {
+	sum := 0
+	for _, b := range buf {
+		sum += int(b)
+	}
+	return sum
+}
+
+// serveRequests runs the load. With a nil pool every request makes a fresh
+// buffer; otherwise it borrows one from pool and puts it back when done.
+func serveRequests(pool *sync.Pool) {
+	var wg sync.WaitGroup
+	for w := 0; w < poolWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < poolRequests; i++ {
+				var buf []byte
+				var bufp *[]byte
+				if pool != nil {
+					bufp = pool.Get().(*[]byte)
+					buf = *bufp
+				} else {
+					buf = make([]byte, poolBufferSize)
+				}
+				buf[i%len(buf)] = byte(i)
+				requestHandler(buf)
+				if pool != nil {
+					pool.Put(bufp)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Demonstrate what a sync.Pool saves under load, and how often it hits
+func DemonstratePoolHitRate() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "SYNC.POOL UNDER LOAD: HIT RATE")
+	fmt.Fprintln(out, "============================================================")
+	requests := poolWorkers * poolRequests
+	fmt.Fprintf(out, "  %d goroutines x %d requests, one %d-byte buffer each\n", poolWorkers, poolRequests, poolBufferSize)
+
+	fresh := TrackMemory("fresh buffer per request", func() { serveRequests(nil) })
+
+	// The pool holds *[]byte and gets the same pointer back: putting the
+	// slice itself in an any would box its header on every Put
+	var misses atomic.Int64
+	pool := sync.Pool{New: func() any {
+		misses.Add(1)
+		buf := make([]byte, poolBufferSize)
+		return &buf
+	}}
+	pooled := TrackMemory("sync.Pool buffer per request", func() { serveRequests(&pool) })
+
+	hits := int64(requests) - misses.Load()
+	hitRate := 100 * float64(hits) / float64(requests)
+	fmt.Fprintf(out, "\n  %-12s %12s %10s %10s\n", "", "Bytes", "Mallocs", "GC cycles")
+	fmt.Fprintf(out, "  %-12s %12d %10d %10d\n", "Fresh", fresh.TotalAlloc, fresh.Mallocs, fresh.GCCycles)
+	fmt.Fprintf(out, "  %-12s %12d %10d %10d\n", "sync.Pool", pooled.TotalAlloc, pooled.Mallocs, pooled.GCCycles)
+	fmt.Fprintf(out, "\n  Pool hits: %d of %d Gets (%.2f%%), New called %d times\n", hits, requests, hitRate, misses.Load())
+	observe("pool hit rate", hitRate, "%")
+
+	fmt.Fprintln(out, "\n  Fewer allocations mean fewer GC cycles: each request's buffer comes back")
+	fmt.Fprintln(out, "  from the pool instead of becoming garbage. The pool keeps a private slot")
+	fmt.Fprintln(out, "  per P, so the steady state hits almost every time; misses come from the")
+	fmt.Fprintln(out, "  first Get on each P and from collections, which empty the pool.")
+}
+
+// How many values each tiny-allocator row allocates
+const tinyValues = 10_000
+
+// newEach fills sink with freshly allocated zero values of T; the sink is
+// global, so each one escapes to the heap
+func newEach[T any](sink []*T) {
+	for i := range sink {
+		sink[i] = new(T)
+	}
+}
+
+// pointerBox is 8 bytes like an int64, but holds a pointer
+type pointerBox struct{ p *int }
+
+// Sinks for the tiny-allocator rows, allocated outside the measurements
+var (
+	tinyUint32s  = make([]*uint32, tinyValues)
+	tinyInt64s   = make([]*int64, tinyValues)
+	tinyArrays   = make([]*[12]byte, tinyValues)
+	tinyPointers = make([]*pointerBox, tinyValues)
+	tinyBlocks   = make([]*[16]byte, tinyValues)
+)
+
+// Demonstrate the tiny allocator packing small pointer-free values together
+func DemonstrateTinyAllocator() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "THE TINY ALLOCATOR: SMALL POINTER-FREE VALUES SHARE BLOCKS")
+	fmt.Fprintln(out, "============================================================")
+
+	rows := []struct {
+		label string
+		fn    func()
+	}{
+		{"*uint32 (4B, no pointers)", func() { newEach(tinyUint32s) }},
+		{"*int64 (8B, no pointers)", func() { newEach(tinyInt64s) }},
+		{"*[12]byte (12B, no pointers)", func() { newEach(tinyArrays) }},
+		{"*pointerBox (8B, a pointer)", func() { newEach(tinyPointers) }},
+		{"*[16]byte (16B, too big)", func() { newEach(tinyBlocks) }},
+	}
+	fmt.Fprintf(out, "  %d new(T) calls per row, every value kept alive\n\n", tinyValues)
+	fmt.Fprintf(out, "  %-30s %8s %8s %12s %11s %11s\n", "Type", "Mallocs", "Frees", "HeapObjects", "TotalAlloc", "Bytes/value")
+	for _, r := range rows {
+		m := MeasureMemory(r.label, r.fn)
+		perValue := float64(m.TotalAlloc) / tinyValues
+		fmt.Fprintf(out, "  %-30s %8d %8d %12d %11d %11.1f\n", r.label, m.Mallocs, m.Frees, m.HeapObjects, m.TotalAlloc, perValue)
+		observe(r.label+" bytes per value", perValue, "bytes")
+	}
+	clear(tinyUint32s)
+	clear(tinyInt64s)
+	clear(tinyArrays)
+	clear(tinyPointers)
+	clear(tinyBlocks)
+
+	fmt.Fprintln(out, "\n  Pointer-free allocations under 16 bytes don't get an object of their own:")
+	fmt.Fprintln(out, "  each P carves them out of a shared 16-byte block, aligned to their size,")
+	fmt.Fprintln(out, "  so four uint32s or two int64s fit in one. MemStats counts the value that")
+	fmt.Fprintln(out, "  opens a block as an allocation; each one packed in after it goes into")
+	fmt.Fprintln(out, "  Mallocs and, right away, Frees, so HeapObjects and TotalAlloc only see the")
+	fmt.Fprintln(out, "  blocks. A [12]byte leaves no room for another, and a value with a pointer")
+	fmt.Fprintln(out, "  never qualifies - the GC scans it, so it needs its own slot in the 8-byte")
+	fmt.Fprintln(out, "  class. The catch: a block is freed only when every value in it is dead, so")
+	fmt.Fprintln(out, "  one live int64 can pin 16 bytes. Rust's Box<u32> asks the allocator for")
+	fmt.Fprintln(out, "  exactly 4 bytes, and a malloc rounds that up to its own smallest class (8")
+	fmt.Fprintln(out, "  or 16 bytes in most).")
+}
+
+// How many objects each large-object row allocates and keeps alive
+const largeObjectCount = 256
+
+// Sink keeping each large-object row's allocations alive while measured
+var largeObjectsHeld [][]byte
+
+// largeObjectRow is what allocating largeObjectCount objects of one size
+// cost: the heap pages they took, the collections and the time
+type largeObjectRow struct {
+	size      int
+	heapInuse uint64 // bytes of spans put in use
+	gcCycles  uint32
+	perAlloc  time.Duration
+}
+
+// allocateLargeObjects allocates and keeps largeObjectCount objects of
+// size bytes, measuring what the heap had to provide for them
+func allocateLargeObjects(size int) largeObjectRow {
+	var before, after memSnapshot
+	largeObjectsHeld = make([][]byte, largeObjectCount)
+	runtime.GC()
+	readSnapshot(&before)
+	start := time.Now()
+	for i := range largeObjectsHeld {
+		largeObjectsHeld[i] = make([]byte, size)
+	}
+	elapsed := time.Since(start)
+	readSnapshot(&after)
+	largeObjectsHeld = nil
+
+	return largeObjectRow{
+		size:      size,
+		heapInuse: after.HeapInuse - min(before.HeapInuse, after.HeapInuse),
+		gcCycles:  after.NumGC - before.NumGC,
+		perAlloc:  elapsed / largeObjectCount,
+	}
+}
+
+// Demonstrate the separate allocation path for objects over 32KB
+func DemonstrateLargeObjects() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "THE LARGE-OBJECT PATH: JUST UNDER AND JUST OVER 32KB")
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintf(out, "  %d objects of each size, all kept alive\n\n", largeObjectCount)
+	fmt.Fprintf(out, "  %-30s %12s %9s %10s %10s\n", "Size", "Heap per obj", "Overhead", "GC cycles", "Per alloc")
+
+	for _, size := range []int{1 << 10, maxSmallSize, maxSmallSize + 1, 1 << 20} {
+		r := allocateLargeObjects(size)
+		perObject := r.heapInuse / largeObjectCount
+		path := "size class"
+		if size > maxSmallSize {
+			path = "large object"
+		}
+		overhead := 100 * max(float64(perObject)/float64(size)-1, 0)
+		fmt.Fprintf(out, "  %-30s %12s %8.1f%% %10d %10v\n",
+			fmt.Sprintf("%d B (%s)", size, path), formatBytes(perObject), overhead, r.gcCycles, r.perAlloc)
+		observe(fmt.Sprintf("heap bytes per %d-byte object", size), float64(perObject), "bytes")
+	}
+
+	fmt.Fprintln(out, "\n  Up to 32KB, an allocation is a size class: the P's cached span hands out")
+	fmt.Fprintln(out, "  the next free slot without a lock, and a span holds many objects (or, at")
+	fmt.Fprintln(out, "  32KB, exactly one). One byte more and the object skips the size classes:")
+	fmt.Fprintln(out, "  the heap allocates it a span of its own, rounded up to whole 8KB pages,")
+	fmt.Fprintln(out, "  under the heap lock - so 32KB+1 costs 40KB, and each one is a span the")
+	fmt.Fprintln(out, "  GC tracks and frees as a unit. At 1MB the rounding is negligible but every")
+	fmt.Fprintln(out, "  allocation must be zeroed, reused pages included, which dominates its time.")
+	fmt.Fprintln(out, "  largeAllocation()'s 1MB slice takes this path; createLargeObject's 1KB")
+	fmt.Fprintln(out, "  array doesn't. Rust's allocators draw the same line (jemalloc at 16KB,")
+	fmt.Fprintln(out, "  glibc malloc mmaps past 128KB), without a GC counting the result.")
+}
@@ -0,0 +1,20 @@
+//go:build !goexperiment.arenas
+
+package main
+
+import "fmt"
+
+// Stand-in for arena.go when the arenas experiment is not enabled
+
+func init() {
+	Register(newDemo("arena", "Bulk-allocate in an arena and free it with one call", DemonstrateArena).in(categoryAllocator))
+}
+
+// DemonstrateArena explains how to enable the real arena demonstration
+func DemonstrateArena() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ARENA ALLOCATION")
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintln(out, "  Arenas are experimental - rebuild with the experiment enabled:")
+	fmt.Fprintln(out, "  GOEXPERIMENT=arenas go run . -demo=arena")
+}
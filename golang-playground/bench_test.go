@@ -0,0 +1,216 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+// Benchmarks for the memory_tracking.go examples: go test -bench=. -benchmem
+// backs TrackMemory's stack-vs-heap claims with per-operation numbers that
+// can be set against Rust benchmarks of the same scenarios.
+
+var largeObjectSink *LargeObject
+
+func BenchmarkStackOnlyAllocation(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stackOnlyAllocation() // expect 0 allocs/op
+	}
+}
+
+func BenchmarkStackStructAllocation(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stackStructAllocation() // expect 0 allocs/op
+	}
+}
+
+func BenchmarkCreateLargeObject(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		largeObjectSink = createLargeObject(i) // expect 2 allocs/op: struct + 1KB Data
+	}
+}
+
+func BenchmarkHeapAllocationViaPointer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		heapAllocationViaPointer() // expect 20 allocs/op: 10 x createLargeObject
+	}
+}
+
+func BenchmarkLargeAllocation(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		largeAllocation() // expect 1 alloc/op: 1MB is past the 64KB stack limit for make
+	}
+}
+
+func BenchmarkUserReturnValue(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		userValueSink = newUserValue("Alice", 30)
+	}
+}
+
+func BenchmarkUserReturnPointer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		userPtrSink = newUserPointer("Alice", 30)
+	}
+}
+
+// Both counter benchmarks increment one shared counter from GOMAXPROCS
+// goroutines (b.RunParallel). The atomic add is a single locked instruction;
+// the mutex adds lock/unlock and parking under contention - but a mutex can
+// guard any critical section, while atomics only cover single-word updates.
+
+func BenchmarkMutexCounter(b *testing.B) {
+	b.ReportAllocs()
+	var mu sync.Mutex
+	var counter int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			counter++
+			mu.Unlock()
+		}
+	})
+	if counter != int64(b.N) {
+		b.Fatalf("counter = %d, want %d", counter, b.N)
+	}
+}
+
+func BenchmarkAtomicCounter(b *testing.B) {
+	b.ReportAllocs()
+	var counter atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			counter.Add(1)
+		}
+	})
+	if got := counter.Load(); got != int64(b.N) {
+		b.Fatalf("counter = %d, want %d", got, b.N)
+	}
+}
+
+// False sharing: falseSharingGoroutines goroutines each increment their own
+// counter, b.N increments in total, so ns/op is the cost of one increment
+// with the others running. -cpu=1 shows the baseline without parallelism.
+
+func BenchmarkFalseSharing(b *testing.B) {
+	b.Run("adjacent", func(b *testing.B) {
+		var c adjacentCounters
+		incrementEach(c.counters(), b.N/falseSharingGoroutines)
+	})
+	b.Run("padded", func(b *testing.B) {
+		var c paddedCounters
+		incrementEach(c.counters(), b.N/falseSharingGoroutines)
+	})
+}
+
+// Receiver benchmarks: a value receiver copies the whole struct into every
+// call, a pointer receiver copies one word. For User (24 bytes) and even
+// LargeObject (32 bytes - the 1KB buffer sits behind the slice header and
+// is never copied) the difference is well under a nanosecond and neither
+// allocates. Prefer value receivers for small immutable types; use pointer
+// receivers when the method mutates, when the struct is large, or when it
+// holds a mutex - and in real code keep one receiver kind per type.
+
+var receiverSink int
+
+func BenchmarkValueReceiver(b *testing.B) {
+	b.Run("User", func(b *testing.B) {
+		b.ReportAllocs()
+		u := User{Name: "Alice", Age: 30}
+		for i := 0; i < b.N; i++ {
+			receiverSink = u.ageByValue()
+		}
+	})
+	b.Run("LargeObject", func(b *testing.B) {
+		b.ReportAllocs()
+		o := LargeObject{ID: 1, Data: make([]byte, 1024)}
+		for i := 0; i < b.N; i++ {
+			receiverSink = o.sizeByValue()
+		}
+	})
+}
+
+func BenchmarkPointerReceiver(b *testing.B) {
+	b.Run("User", func(b *testing.B) {
+		b.ReportAllocs()
+		u := &User{Name: "Alice", Age: 30}
+		for i := 0; i < b.N; i++ {
+			receiverSink = u.ageByPointer()
+		}
+	})
+	b.Run("LargeObject", func(b *testing.B) {
+		b.ReportAllocs()
+		o := &LargeObject{ID: 1, Data: make([]byte, 1024)}
+		for i := 0; i < b.N; i++ {
+			receiverSink = o.sizeByPointer()
+		}
+	})
+}
+
+// GC scan cost: the same scanHeapBytes live as one pointer-free slice or as
+// a linked list of 16-byte nodes, each op a forced collection over it.
+// gc-cpu-ns/op is the GC CPU time runtime/metrics accounted per cycle.
+
+func BenchmarkGCScan(b *testing.B) {
+	for _, heap := range []struct {
+		name  string
+		build func()
+	}{
+		{"noscan", buildNoscanHeap},
+		{"pointers", buildPointerHeap},
+	} {
+		b.Run(heap.name, func(b *testing.B) {
+			heap.build()
+			defer dropScanHeaps()
+			runtime.GC()
+			startCPU := gcCPUSeconds()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+			}
+			b.StopTimer()
+			b.ReportMetric((gcCPUSeconds()-startCPU)*1e9/float64(b.N), "gc-cpu-ns/op")
+		})
+	}
+}
+
+// BenchmarkParticles backs the soa-vs-aos demo: layout-B/particle is each
+// layout's footprint (AoS pads every particle), MB/s the rate a loop gets
+// through all of it
+func BenchmarkParticles(b *testing.B) {
+	aos := buildParticleAoS(soaParticles)
+	soa := buildParticleSoA(soaParticles)
+	for _, layout := range []struct {
+		name  string
+		bytes int64
+		sum   func()
+		step  func()
+	}{
+		{"AoS", int64(soaParticles * unsafe.Sizeof(particle{})),
+			func() { particleSumSink += sumAliveXAoS(aos) }, func() { stepAoS(aos) }},
+		{"SoA", int64(len(soa.X)*8*3 + len(soa.Alive)),
+			func() { particleSumSink += sumAliveXSoA(soa) }, func() { stepSoA(soa) }},
+	} {
+		for _, loop := range []struct {
+			name string
+			run  func()
+		}{{"sum-x", layout.sum}, {"step", layout.step}} {
+			b.Run(layout.name+"/"+loop.name, func(b *testing.B) {
+				b.SetBytes(layout.bytes)
+				b.ReportMetric(float64(layout.bytes)/soaParticles, "layout-B/particle")
+				for i := 0; i < b.N; i++ {
+					loop.run()
+				}
+			})
+		}
+	}
+}
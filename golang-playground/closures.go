@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+)
+
+// This file takes escapesViaClosure apart: what a Go closure captures, when
+// that capture costs a heap allocation, and how loops changed in Go 1.22
+
+func init() {
+	Register(newDemo("closure-semantics", "Closure captures in loops, by reference vs by value, stack vs heap", DemonstrateClosureSemantics).in(categoryEscape))
+}
+
+// Sinks keeping returned closures reachable after the measured call
+var (
+	capturedFuncSink  func() int
+	capturedFuncsSink []func() int
+)
+
+// How many closures the loop examples build
+const loopClosures = 3
+
+// Called in place: the closure and x both stay in the frame
+func closureCalledLocally() int {
+	x := 42
+	f := func() int { return x }
+	return f()
+}
+
+// applyTo calls f without keeping it; kept out of line so the closure
+// really is passed, not inlined away
+//
+//go:noinline
+func applyTo(f func(int) int, v int) int { return f(v) }
+
+// Passed down to a callee that doesn't retain it: still the stack
+func closurePassedDown() int {
+	k := 7
+	return applyTo(func(v int) int { return v + k }, 35)
+}
+
+// Returned: the closure outlives the frame, so it goes to the heap. x is
+// never reassigned, so the compiler copies it into the closure - one object.
+func closureReturned() func() int {
+	x := 42
+	return func() int { return x }
+}
+
+// Returned and mutating its capture: n must be shared by reference, so n
+// moves to the heap on its own, next to the closure - two objects
+func closureReturnedMutating() func() int {
+	n := 0
+	return func() int {
+		n++
+		return n
+	}
+}
+
+// Go 1.22+: each iteration has its own i, so each closure sees its own value
+func closuresPerIteration() []func() int {
+	funcs := make([]func() int, 0, loopClosures)
+	for i := 0; i < loopClosures; i++ {
+		funcs = append(funcs, func() int { return i })
+	}
+	return funcs
+}
+
+// Before Go 1.22 every loop worked like this: one i for the whole loop,
+// which every closure captures by reference, and which ends at loopClosures
+func closuresSharedVariable() []func() int {
+	funcs := make([]func() int, 0, loopClosures)
+	var i int
+	for i = 0; i < loopClosures; i++ {
+		funcs = append(funcs, func() int { return i })
+	}
+	return funcs
+}
+
+// callAll calls each closure and collects what it returns
+func callAll(funcs []func() int) []int {
+	values := make([]int, len(funcs))
+	for i, f := range funcs {
+		values[i] = f()
+	}
+	return values
+}
+
+// Demonstrate what closures capture and what that costs
+func DemonstrateClosureSemantics() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "CLOSURE CAPTURE SEMANTICS")
+	fmt.Fprintln(out, "============================================================")
+
+	fmt.Fprintf(out, "  Loop closures, go.mod says Go 1.22+: %v\n", callAll(closuresPerIteration()))
+	fmt.Fprintf(out, "  Loop closures over one shared i:     %v (every loop before 1.22)\n", callAll(closuresSharedVariable()))
+	counter := closureReturnedMutating()
+	counter()
+	counter()
+	fmt.Fprintf(out, "  A returned counter, called 3 times:  %d (n is shared, not copied)\n\n", counter())
+
+	cases := []struct {
+		label, rust string
+		fn          func()
+	}{
+		{"called locally", "|| x borrows; the closure is a stack value", func() { _ = closureCalledLocally() }},
+		{"passed to a non-retaining callee", "f: impl Fn(i32) -> i32, monomorphized", func() { _ = closurePassedDown() }},
+		{"returned, capture never reassigned", "move || x, returned as impl Fn: no allocation", func() { capturedFuncSink = closureReturned() }},
+		{"returned, capture mutated", "move || { n += 1; n }: FnMut owns n", func() { capturedFuncSink = closureReturnedMutating() }},
+		{fmt.Sprintf("%d in a loop, per-iteration i", loopClosures), "for i in 0..3 { v.push(move || i) }", func() { capturedFuncsSink = closuresPerIteration() }},
+		{fmt.Sprintf("%d in a loop, shared i", loopClosures), "rejected: closures may outlive i", func() { capturedFuncsSink = closuresSharedVariable() }},
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Closure\tallocs/op\tRust equivalent")
+	for _, c := range cases {
+		fmt.Fprintf(tw, "  %s\t%.0f\t%s\n", c.label, MeasureAllocs(c.label, c.fn), c.rust)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(out, "\n  Go closures capture variables, not values: the closure and the enclosing")
+	fmt.Fprintln(out, "  function see the same variable. The compiler copies a capture instead only")
+	fmt.Fprintln(out, "  when nobody can tell - small and never reassigned after capture. A")
+	fmt.Fprintln(out, "  closure that stays in its frame costs nothing; one that escapes is a heap")
+	fmt.Fprintln(out, "  object, plus one per variable it shares by reference (the loop slice adds")
+	fmt.Fprintln(out, "  one more). Go 1.22 made loop variables per-iteration, so the loop closures")
+	fmt.Fprintln(out, "  see 0 1 2 - and can be copied - where the shared i made them all see 3.")
+	fmt.Fprintln(out, "  Rust spells the choice out: a plain closure borrows, `move` takes")
+	fmt.Fprintln(out, "  ownership, and a returned impl Fn is a plain value; only Box<dyn Fn>")
+	fmt.Fprintln(out, "  allocates, and the borrow checker rejects what the shared i did.")
+	capturedFuncSink, capturedFuncsSink = nil, nil
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unsafe"
+)
+
+// This file compares the memory footprint of Go's built-in collections
+
+func init() {
+	Register(newDemo("map-vs-slice", "Per-element memory of map[int]int vs []int", DemonstrateMapVsSlice).in(categoryCollections))
+	Register(demo{
+		name:        "nil-vs-empty-slice",
+		description: "Nil vs empty slices: allocation and JSON encoding",
+		category:    categoryCollections,
+		run:         DemonstrateNilVsEmptySlice,
+	})
+	Register(newDemo("map-addressability", "map[string]User copy-back vs map[string]*User in place", DemonstrateMapAddressability).in(categoryCollections))
+	Register(newDemo("append-aliasing", "append within capacity mutates the parent, past it decouples", DemonstrateAppendAliasing).in(categoryCollections))
+	Register(newDemo("append-growth", "Append one element at a time: every reallocation, growth factor and copy (-prealloc: none)", DemonstrateAppendGrowth).in(categoryCollections))
+	Register(newDemo("capped-slice", "s[low:high:max] forces append to copy instead of clobbering", DemonstrateCappedSlice).in(categoryCollections))
+	Register(newDemo("slice-to-array-ptr", "(*[4]byte)(s) aliases the slice's array - no copy", DemonstrateSliceToArrayPtr).in(categoryCollections))
+}
+
+// Package-level sinks keep the collections alive so the compiler
+// can't optimize the allocations away
+var (
+	mapSink   map[int]int
+	sliceSink []int
+
+	userMapSink    map[string]User
+	userPtrMapSink map[string]*User
+
+	arrayPtrSink *[4]byte
+	growthSink   []int
+)
+
+const mapVsSliceElements = 10000
+
+// Store N sequential keys in a map - pays for buckets, tophash bytes and spare slots
+func fillMap(n int) {
+	m := make(map[int]int)
+	for i := 0; i < n; i++ {
+		m[i] = i
+	}
+	mapSink = m
+}
+
+// Store the same N values in a slice - the index IS the key, no overhead
+func fillSlice(n int) {
+	s := make([]int, n)
+	for i := 0; i < n; i++ {
+		s[i] = i
+	}
+	sliceSink = s
+}
+
+// Demonstrate the cost of reaching for a map when a slice would do
+func DemonstrateMapVsSlice() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "MAP VS SLICE MEMORY FOOTPRINT")
+	fmt.Fprintln(out, "============================================================")
+
+	n := mapVsSliceElements
+
+	mapResult := TrackMemory(fmt.Sprintf("map[int]int (%d sequential keys)", n), func() {
+		fillMap(n)
+	})
+	sliceResult := TrackMemory(fmt.Sprintf("[]int (%d elements)", n), func() {
+		fillSlice(n)
+	})
+
+	mapPerElem := float64(mapResult.TotalAlloc) / float64(n)
+	slicePerElem := float64(sliceResult.TotalAlloc) / float64(n)
+
+	fmt.Fprintln(out, "\n  Per-element cost:")
+	fmt.Fprintf(out, "  map[int]int: %6.1f bytes/element\n", mapPerElem)
+	fmt.Fprintf(out, "  []int:       %6.1f bytes/element\n", slicePerElem)
+	if slicePerElem > 0 {
+		fmt.Fprintf(out, "  The map uses %.1fx the memory of the slice\n", mapPerElem/slicePerElem)
+	}
+	fmt.Fprintln(out, "  Why: the map stores keys AND values in buckets, keeps control bytes,")
+	fmt.Fprintln(out, "  grows by doubling before it is full (load factor), and rehashes as it grows")
+	fmt.Fprintln(out, "  The slice stores only the values - the index is the key")
+
+	mapSink = nil
+	sliceSink = nil
+}
+
+// Declare a nil slice - no backing array exists at all
+func makeNilSlice() {
+	var s []int
+	sliceSink = s
+}
+
+// Declare an empty, non-nil slice - needs a (zero-sized) backing array
+func makeEmptySlice() {
+	s := []int{}
+	sliceSink = s
+}
+
+// Demonstrate that nil and empty slices look alike but are not the same
+func DemonstrateNilVsEmptySlice() error {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "NIL SLICE VS EMPTY SLICE")
+	fmt.Fprintln(out, "============================================================")
+
+	var nilSlice []int
+	emptySlice := []int{}
+
+	fmt.Fprintf(out, "  var s []int: nil=%-5t len=%d cap=%d data=%p\n",
+		nilSlice == nil, len(nilSlice), cap(nilSlice), unsafe.SliceData(nilSlice))
+	fmt.Fprintf(out, "  s := []int{}: nil=%-5t len=%d cap=%d data=%p\n",
+		emptySlice == nil, len(emptySlice), cap(emptySlice), unsafe.SliceData(emptySlice))
+	fmt.Fprintln(out, "  Both range over zero elements and both accept append")
+
+	TrackMemory("Nil slice (var s []int)", func() {
+		makeNilSlice()
+	})
+	TrackMemory("Empty slice literal ([]int{})", func() {
+		makeEmptySlice()
+	})
+	fmt.Fprintln(out, "\n  The nil slice has no backing array; the empty literal points at a")
+	fmt.Fprintln(out, "  zero-size array (the runtime shares one address for all of them)")
+
+	nilJSON, err := json.Marshal(nilSlice)
+	if err != nil {
+		return fmt.Errorf("marshal nil slice: %w", err)
+	}
+	emptyJSON, err := json.Marshal(emptySlice)
+	if err != nil {
+		return fmt.Errorf("marshal empty slice: %w", err)
+	}
+	fmt.Fprintf(out, "\n  json.Marshal(nil slice):   %s\n", nilJSON)
+	fmt.Fprintf(out, "  json.Marshal(empty slice): %s\n", emptyJSON)
+	fmt.Fprintln(out, "  An API returning a nil slice sends null, not [] - clients notice!")
+
+	sliceSink = nil
+	return nil
+}
+
+// A typical helper: looks harmless, but writes into the caller's array
+// whenever s has spare capacity
+func appendMarker(s []int, marker int) []int {
+	return append(s, marker)
+}
+
+// Demonstrate both sides of the append hazard: aliasing, then decoupling
+func DemonstrateAppendAliasing() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "APPEND ALIASING")
+	fmt.Fprintln(out, "============================================================")
+
+	parent := make([]int, 4, 6)
+	for i := range parent {
+		parent[i] = i + 1
+	}
+	child := parent[:2]
+	fmt.Fprintf(out, "  parent: %v len=%d cap=%d data=%p\n", parent, len(parent), cap(parent), unsafe.SliceData(parent))
+	fmt.Fprintf(out, "  child := parent[:2]       len=%d cap=%d data=%p\n", len(child), cap(child), unsafe.SliceData(child))
+
+	// Case 1: within capacity - the write lands in parent's array
+	child = appendMarker(child, 99)
+	fmt.Fprintf(out, "\n  child = append(child, 99) len=%d cap=%d data=%p (same array)\n", len(child), cap(child), unsafe.SliceData(child))
+	fmt.Fprintf(out, "  parent: %v <- parent[2] was 3, the caller never asked for this\n", parent)
+
+	// Case 2: past capacity - append copies to a new array and the slices decouple
+	for len(child) <= cap(parent) {
+		child = appendMarker(child, 100+len(child))
+	}
+	fmt.Fprintf(out, "\n  append past cap(%d):       len=%d cap=%d data=%p (new array)\n", cap(parent), len(child), cap(child), unsafe.SliceData(child))
+	child[0] = -1
+	fmt.Fprintf(out, "  child[0] = -1 -> child: %v\n", child)
+	fmt.Fprintf(out, "  parent: %v <- parent[0] unaffected (103 leaked in while child still fit)\n", parent)
+
+	fmt.Fprintln(out, "\n  Whether append mutates shared memory depends on RUNTIME capacity, not on")
+	fmt.Fprintln(out, "  anything visible in the code. Fixes: cap the slice you hand out")
+	fmt.Fprintln(out, "  (s[:n:n], see capped-slice) or copy it (slices.Clone) before appending.")
+}
+
+const appendGrowthElements = 2000
+
+// appendGrowthStep is one reallocation append made: the length it happened
+// at, the capacities before and after, and whether the array moved
+type appendGrowthStep struct {
+	len, oldCap, newCap int
+	oldData, newData    *int
+}
+
+// appendOneByOne appends n elements to s, recording every capacity change
+func appendOneByOne(s []int, n int) ([]int, []appendGrowthStep) {
+	var steps []appendGrowthStep
+	for i := range n {
+		oldCap, oldData := cap(s), unsafe.SliceData(s)
+		s = append(s, i)
+		if cap(s) != oldCap || unsafe.SliceData(s) != oldData {
+			steps = append(steps, appendGrowthStep{len: len(s) - 1, oldCap: oldCap, newCap: cap(s), oldData: oldData, newData: unsafe.SliceData(s)})
+		}
+	}
+	return s, steps
+}
+
+// Demonstrate append's growth strategy, one element at a time
+func DemonstrateAppendGrowth() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "APPEND GROWTH")
+	fmt.Fprintln(out, "============================================================")
+
+	var start []int
+	if *preallocFlag {
+		start = make([]int, 0, appendGrowthElements)
+	}
+	fmt.Fprintf(out, "  Appending %d ints one at a time to a slice with cap=%d\n", appendGrowthElements, cap(start))
+	s, steps := appendOneByOne(start, appendGrowthElements)
+	growthSink = s
+
+	if len(steps) > 0 {
+		fmt.Fprintf(out, "\n  %6s %12s %8s %14s  %s\n", "len", "cap", "factor", "copied", "backing array")
+	}
+	copied := 0
+	for _, st := range steps {
+		factor, moved := "-", "allocated"
+		if st.oldCap > 0 {
+			factor = fmt.Sprintf("x%.2f", float64(st.newCap)/float64(st.oldCap))
+		}
+		if st.oldData != nil {
+			moved = fmt.Sprintf("%p -> %p", st.oldData, st.newData)
+		}
+		copied += st.len
+		fmt.Fprintf(out, "  %6d %5d->%-5d %8s %14s  %s\n", st.len, st.oldCap, st.newCap, factor, formatBytes(uint64(st.len)*uint64(unsafe.Sizeof(0))), moved)
+	}
+	fmt.Fprintf(out, "\n  %d reallocations, %d elements (%s) copied to end at len=%d cap=%d\n",
+		len(steps), copied, formatBytes(uint64(copied)*uint64(unsafe.Sizeof(0))), len(s), cap(s))
+	observe("append reallocations", float64(len(steps)), "count")
+	observe("append elements copied", float64(copied), "count")
+
+	if *preallocFlag {
+		fmt.Fprintln(out, "\n  With make([]int, 0, n) append never runs out of room: the array is")
+		fmt.Fprintln(out, "  allocated once, nothing is copied, and every address stays valid. That's")
+		fmt.Fprintln(out, "  Vec::with_capacity(n) in Rust. Run without -prealloc to see the growth.")
+		return
+	}
+	fmt.Fprintln(out, "\n  When len reaches cap, append allocates a bigger array, copies every")
+	fmt.Fprintln(out, "  element over and leaves the old one to the GC. Small slices double;")
+	fmt.Fprintln(out, "  past 256 elements the factor eases towards 1.25, and each new cap is")
+	fmt.Fprintln(out, "  rounded up to fill its size class, which is why the factors wobble.")
+	fmt.Fprintln(out, "  Copies add up to a small multiple of the final length, so appending stays")
+	fmt.Fprintln(out, "  amortized O(1), but any other slice or pointer into the old array now")
+	fmt.Fprintln(out, "  sees stale data (see append-aliasing). Rust's Vec doubles its capacity")
+	fmt.Fprintln(out, "  and the borrow checker forbids holding a reference across the push.")
+	fmt.Fprintln(out, "  Rerun with -prealloc to start from make([]int, 0, n): zero reallocations.")
+}
+
+// Demonstrate the full slice expression s[low:high:max] as the fix for append aliasing
+func DemonstrateCappedSlice() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "CAPPED SLICES (s[low:high:max])")
+	fmt.Fprintln(out, "============================================================")
+
+	// The hazard: spare capacity means append writes into the parent's array
+	original := []int{1, 2, 3, 4, 5}
+	uncapped := original[1:3]
+	fmt.Fprintf(out, "  uncapped := original[1:3]   len=%d cap=%d data=%p\n", len(uncapped), cap(uncapped), unsafe.SliceData(uncapped))
+	uncapped = append(uncapped, 99)
+	fmt.Fprintf(out, "  after append(uncapped, 99)  len=%d cap=%d data=%p (same array)\n", len(uncapped), cap(uncapped), unsafe.SliceData(uncapped))
+	fmt.Fprintf(out, "  original: %v <- original[3] clobbered!\n", original)
+
+	// The fix: cap == len, so the first append must reallocate
+	original = []int{1, 2, 3, 4, 5}
+	capped := original[1:3:3]
+	fmt.Fprintf(out, "\n  capped := original[1:3:3]   len=%d cap=%d data=%p\n", len(capped), cap(capped), unsafe.SliceData(capped))
+	capped = append(capped, 99)
+	fmt.Fprintf(out, "  after append(capped, 99)    len=%d cap=%d data=%p (new array)\n", len(capped), cap(capped), unsafe.SliceData(capped))
+	fmt.Fprintf(out, "  original: %v <- untouched\n", original)
+	fmt.Fprintf(out, "  capped:   %v\n", capped)
+
+	fmt.Fprintln(out, "\n  Hand out s[low:high:high] whenever the callee might append - it costs")
+	fmt.Fprintln(out, "  a copy on the first append instead of silently corrupting your data")
+}
+
+const addressabilityUsers = 1000
+
+// Struct values live inside the map's buckets - no allocation per user,
+// but m[key].Age++ doesn't compile (map elements aren't addressable):
+//
+//	m[key].Age++ // error: cannot assign to struct field m[key].Age in map
+//
+// so every update is read-modify-write on a copy
+func updateValueMap(keys []string) {
+	m := make(map[string]User, len(keys))
+	for i, key := range keys {
+		m[key] = User{Name: "user", Age: i}
+	}
+	for key := range m {
+		u := m[key] // copy out
+		u.Age++
+		m[key] = u // copy back
+	}
+	userMapSink = m
+}
+
+// Pointer values can be mutated in place - at the cost of one heap User each
+func updatePointerMap(keys []string) {
+	m := make(map[string]*User, len(keys))
+	for i, key := range keys {
+		m[key] = &User{Name: "user", Age: i}
+	}
+	for key := range m {
+		m[key].Age++ // fine: the map holds a pointer, the User is addressable
+	}
+	userPtrMapSink = m
+}
+
+// Demonstrate why struct-valued maps need the copy-back pattern
+func DemonstrateMapAddressability() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "MAP ELEMENT ADDRESSABILITY")
+	fmt.Fprintln(out, "============================================================")
+
+	// Build the keys up front so their allocations don't pollute the measurements
+	keys := make([]string, addressabilityUsers)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	values := TrackMemory(fmt.Sprintf("map[string]User (%d users, copy-back update)", addressabilityUsers), func() {
+		updateValueMap(keys)
+	})
+	pointers := TrackMemory(fmt.Sprintf("map[string]*User (%d users, in-place update)", addressabilityUsers), func() {
+		updatePointerMap(keys)
+	})
+
+	fmt.Fprintf(out, "\n  Mallocs: %d for values vs %d for pointers\n", values.Mallocs, pointers.Mallocs)
+	fmt.Fprintln(out, "  Map elements move when the map grows, so Go forbids taking their address -")
+	fmt.Fprintln(out, "  m[key].Field = x won't compile. Either copy out, modify and store back,")
+	fmt.Fprintln(out, "  or store pointers (addressable, but one extra heap object per value).")
+	userMapSink = nil
+	userPtrMapSink = nil
+}
+
+// asArray4 converts s to an array pointer, turning the runtime panic for a
+// short slice into an error
+func asArray4(s []byte) (p *[4]byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("convert len %d slice to *[4]byte: %v", len(s), r)
+		}
+	}()
+	return (*[4]byte)(s), nil
+}
+
+// Demonstrate the Go 1.17 slice-to-array-pointer conversion (and Go 1.20's
+// value form) as a zero-copy view of the slice's backing array
+func DemonstrateSliceToArrayPtr() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "SLICE TO ARRAY POINTER")
+	fmt.Fprintln(out, "============================================================")
+
+	buf := []byte{1, 2, 3, 4, 5, 6}
+	result := TrackMemory("(*[4]byte)(buf) conversion", func() {
+		arrayPtrSink, _ = asArray4(buf)
+	})
+	header := arrayPtrSink
+	fmt.Fprintf(out, "\n  buf data=%p  header=%p (same address)\n", unsafe.SliceData(buf), header)
+
+	header[0] = 99
+	fmt.Fprintf(out, "  header[0] = 99 -> buf: %v\n", buf)
+	fmt.Fprintf(out, "  Conversion allocated %d bytes: it is a pointer to buf's first 4 bytes\n", result.TotalAlloc)
+
+	// The value form ([4]byte)(s), added in Go 1.20, copies instead
+	copied := [4]byte(buf)
+	copied[1] = 77
+	fmt.Fprintf(out, "  copied := [4]byte(buf); copied[1] = 77 -> buf: %v (unchanged)\n", buf)
+
+	if _, err := asArray4(buf[:3]); err != nil {
+		fmt.Fprintf(out, "\n  Too short: %v\n", err)
+	}
+
+	fmt.Fprintln(out, "\n  (*[N]T)(s) gives fixed-size, bounds-check-free access without copying -")
+	fmt.Fprintln(out, "  like Rust's <&[u8; 4]>::try_from(&s[..]). But where Rust returns a")
+	fmt.Fprintln(out, "  Result and the borrow checker freezes the slice, Go panics when")
+	fmt.Fprintln(out, "  len(s) < N and lets both views mutate the same bytes.")
+	arrayPtrSink = nil
+}
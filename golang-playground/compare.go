@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang-playground/schema"
+)
+
+// This file lines up Go measurements against the Rust playground's, and
+// two Go demonstrations against each other
+
+// parseResults decodes either a versioned Report or a bare JSON array of
+// MemResult; see schema.Decode for which versions it accepts
+func parseResults(data []byte) ([]MemResult, error) {
+	report, err := schema.Decode(data)
+	return report.Results, err
+}
+
+// readResults reads and decodes the results file at path; side names the
+// file in errors
+func readResults(side, path string) ([]MemResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s results: %w", side, err)
+	}
+	results, err := parseResults(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s results %s: %w", side, path, err)
+	}
+	return results, nil
+}
+
+// CompareFiles prints the Go results in goResultsPath next to the Rust
+// results in rustResultsPath, without running anything. Both files follow
+// the schema package; the Go one is what -format=json writes.
+func CompareFiles(goResultsPath, rustResultsPath string) error {
+	goResults, err := readResults("go", goResultsPath)
+	if err != nil {
+		return err
+	}
+	rustResults, err := readResults("rust", rustResultsPath)
+	if err != nil {
+		return err
+	}
+	printComparison(goResults, rustResults)
+	return nil
+}
+
+// CompareWithRust runs the Go demos and prints their total allocations next
+// to the Rust measurements in rustResultsPath (a Report or MemResult array)
+func CompareWithRust(rustResultsPath string) error {
+	rustResults, err := readResults("rust", rustResultsPath)
+	if err != nil {
+		return err
+	}
+
+	goResults, err := collectResults(func() error {
+		return runDemos(defaultDemos())
+	})
+	if err != nil {
+		return err
+	}
+
+	printComparison(goResults, rustResults)
+	return nil
+}
+
+// printComparison prints one row per scenario name; a scenario measured on
+// only one side gets a blank cell on the other
+func printComparison(goResults, rustResults []MemResult) {
+	goByName := make(map[string]MemResult, len(goResults))
+	rustByName := make(map[string]MemResult, len(rustResults))
+	var names []string
+	for _, r := range goResults {
+		if _, seen := goByName[r.Name]; !seen {
+			names = append(names, r.Name)
+		}
+		goByName[r.Name] = r
+	}
+	for _, r := range rustResults {
+		if _, seen := goByName[r.Name]; !seen {
+			if _, seen := rustByName[r.Name]; !seen {
+				names = append(names, r.Name)
+			}
+		}
+		rustByName[r.Name] = r
+	}
+
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "GO VS RUST")
+	fmt.Fprintln(out, "============================================================")
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Scenario\tGo bytes\tRust bytes\tGo/Rust\tGo allocs\tRust allocs\tGo time\tRust time\t")
+	for _, name := range names {
+		goResult, inGo := goByName[name]
+		rustResult, inRust := rustByName[name]
+
+		var goCells, rustCells [3]string
+		if inGo {
+			goCells = comparisonCells(goResult)
+		}
+		if inRust {
+			rustCells = comparisonCells(rustResult)
+		}
+		ratio := ""
+		if inGo && inRust && rustResult.TotalAlloc > 0 {
+			ratio = fmt.Sprintf("%.2fx", float64(goResult.TotalAlloc)/float64(rustResult.TotalAlloc))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", name,
+			goCells[0], rustCells[0], ratio, goCells[1], rustCells[1], goCells[2], rustCells[2])
+	}
+	w.Flush()
+	fmt.Fprintln(out, "\n  A blank cell means that side has no scenario by that name.")
+}
+
+// comparisonCells renders the bytes, allocations and time of r for printComparison
+func comparisonCells(r MemResult) [3]string {
+	return [3]string{
+		strconv.FormatUint(r.TotalAlloc, 10),
+		strconv.FormatUint(r.Mallocs, 10),
+		r.Duration.String(),
+	}
+}
+
+// CompareDemos runs the two demonstrations named in spec ("nameA,nameB"),
+// each as a whole under MeasureMemory, and prints CompareMemory for them
+func CompareDemos(spec string) error {
+	names := strings.Split(spec, ",")
+	if len(names) != 2 || names[0] == "" || names[1] == "" {
+		return fmt.Errorf("-compare wants two demo names as nameA,nameB, got %q", spec)
+	}
+
+	var results [2]MemResult
+	for i, name := range names {
+		d, err := findDemo(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		var runErr error
+		results[i] = MeasureMemory(d.Name(), func() { runErr = d.Run() })
+		if runErr != nil {
+			return fmt.Errorf("%s: %w", d.Name(), runErr)
+		}
+	}
+
+	CompareMemory(results[0], results[1])
+	return nil
+}
+
+// CompareMemory prints a and b side by side with the difference b - a and
+// the ratio b/a for each counter
+func CompareMemory(a, b MemResult) {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintf(out, "COMPARISON: %s VS %s\n", a.Name, b.Name)
+	fmt.Fprintln(out, "============================================================")
+
+	rows := []struct {
+		metric string
+		a, b   int64
+	}{
+		{"Total bytes", int64(a.TotalAlloc), int64(b.TotalAlloc)},
+		{"Heap bytes", int64(a.HeapAlloc), int64(b.HeapAlloc)},
+		{"Objects", int64(a.HeapObjects), int64(b.HeapObjects)},
+		{"Mallocs", int64(a.Mallocs), int64(b.Mallocs)},
+		{"Frees", int64(a.Frees), int64(b.Frees)},
+		{"GC cycles", int64(a.GCCycles), int64(b.GCCycles)},
+		{"Elapsed (ns)", a.Duration.Nanoseconds(), b.Duration.Nanoseconds()},
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "Metric\t%s\t%s\tDiff\tRatio\t\n", a.Name, b.Name)
+	for _, r := range rows {
+		ratio := ""
+		if r.a != 0 {
+			ratio = fmt.Sprintf("%.2fx", float64(r.b)/float64(r.a))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%+d\t%s\t\n", r.metric, r.a, r.b, r.b-r.a, ratio)
+	}
+	w.Flush()
+}
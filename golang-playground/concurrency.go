@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// This file covers memory-model topics that only show up with several goroutines
+
+func init() {
+	Register(newDemo("false-sharing", "Adjacent vs cache-line-padded counters under two goroutines", DemonstrateFalseSharing).in(categoryLayout))
+	Register(newDemo("happens-before", "A channel send happens-before the receive", DemonstrateHappensBefore).in(categoryMemoryModel))
+	Register(newDemo("ownership-transfer", "Handing buffers between goroutines through channels", DemonstrateOwnershipTransfer).in(categoryMemoryModel))
+}
+
+// False sharing setup: one goroutine per counter
+const (
+	falseSharingGoroutines = 4
+	falseSharingIterations = 5_000_000
+	cacheLineSize          = 64
+)
+
+// Counters side by side - 4 x 8 bytes, all in one 64-byte cache line
+type adjacentCounters struct {
+	n [falseSharingGoroutines]int64
+}
+
+// paddedCounter fills a whole cache line: an 8-byte counter, 56 bytes of pad
+type paddedCounter struct {
+	n int64
+	_ [cacheLineSize - 8]byte
+}
+
+// The same counters, each on its own cache line
+type paddedCounters struct {
+	n [falseSharingGoroutines]paddedCounter
+}
+
+// counters returns a pointer to each of c's counters
+func (c *adjacentCounters) counters() []*int64 {
+	ptrs := make([]*int64, len(c.n))
+	for i := range c.n {
+		ptrs[i] = &c.n[i]
+	}
+	return ptrs
+}
+
+// counters returns a pointer to each of c's counters
+func (c *paddedCounters) counters() []*int64 {
+	ptrs := make([]*int64, len(c.n))
+	for i := range c.n {
+		ptrs[i] = &c.n[i].n
+	}
+	return ptrs
+}
+
+// incrementEach runs one goroutine per counter, each adding 1 to its own
+// counter iterations times, and waits for all of them
+func incrementEach(counters []*int64, iterations int) {
+	var wg sync.WaitGroup
+	for _, counter := range counters {
+		wg.Add(1)
+		go func(c *int64) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				atomic.AddInt64(c, 1)
+			}
+		}(counter)
+	}
+	wg.Wait()
+}
+
+// Run incrementEach over counters and return ns per increment, per goroutine
+func timeCounters(counters []*int64) float64 {
+	start := time.Now()
+	incrementEach(counters, falseSharingIterations)
+	return float64(time.Since(start).Nanoseconds()) / falseSharingIterations
+}
+
+// Demonstrate false sharing: independent data, shared cache line, contended anyway
+func DemonstrateFalseSharing() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "FALSE SHARING")
+	fmt.Fprintln(out, "============================================================")
+
+	var adjacent adjacentCounters
+	var padded paddedCounters
+
+	adjacentNs := timeCounters(adjacent.counters())
+	paddedNs := timeCounters(padded.counters())
+
+	fmt.Fprintf(out, "  %d goroutines x %d increments each\n", falseSharingGoroutines, falseSharingIterations)
+	fmt.Fprintf(out, "  Adjacent counters (%3d-byte struct): %6.2f ns/op\n", unsafe.Sizeof(adjacent), adjacentNs)
+	fmt.Fprintf(out, "  Padded counters   (%3d-byte struct): %6.2f ns/op\n", unsafe.Sizeof(padded), paddedNs)
+	if paddedNs > 0 {
+		fmt.Fprintf(out, "  Adjacent/padded time ratio:          %6.2fx\n", adjacentNs/paddedNs)
+		observe("adjacent/padded time ratio", adjacentNs/paddedNs, "x")
+	}
+	if procs := runtime.GOMAXPROCS(0); procs < 2 {
+		fmt.Fprintf(out, "  Note: GOMAXPROCS=%d - the goroutines never run in parallel, so no false sharing\n", procs)
+	}
+	fmt.Fprintln(out, "\n  The goroutines never touch each other's counter, but CPU caches work in")
+	fmt.Fprintln(out, "  64-byte lines: every write invalidates the line in the other cores' caches,")
+	fmt.Fprintln(out, "  so the line ping-pongs between cores. Padding gives each counter its own line,")
+	fmt.Fprintln(out, "  at the price of 8x the memory. Go has no alignment attribute - padding is a")
+	fmt.Fprintln(out, "  blank array field (golang.org/x/sys/cpu.CacheLinePad sizes it per CPU);")
+	fmt.Fprintln(out, "  Rust says #[repr(align(64))] on the type, or uses crossbeam's CachePadded.")
+	fmt.Fprintln(out, "  Benchmark it: go test -bench=FalseSharing -cpu=1,4")
+}
+
+// Written by one goroutine, read by another - deliberately NOT atomic or locked
+var sharedMessage string
+
+// Demonstrate the memory model's channel rule: a send happens-before the receive
+func DemonstrateHappensBefore() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "HAPPENS-BEFORE VIA CHANNELS")
+	fmt.Fprintln(out, "============================================================")
+
+	done := make(chan struct{})
+	go func() {
+		sharedMessage = "hello from the writer" // (1) plain write
+		done <- struct{}{}                      // (2) send
+	}()
+	<-done                                                 // (3) receive
+	fmt.Fprintf(out, "  Reader sees: %q\n", sharedMessage) // (4) plain read
+
+	// The racy version - DON'T do this:
+	//
+	//	go func() { sharedMessage = "hello from the writer" }()
+	//	fmt.Println(sharedMessage)
+	//
+	// Nothing orders the write before the read, so the reader may see the old
+	// value, the new one, or (for multi-word values like strings) a torn mix.
+	// `go run -race` reports it; Rust refuses to compile it.
+
+	fmt.Fprintln(out, "\n  (1) happens-before (2): same goroutine, program order")
+	fmt.Fprintln(out, "  (2) happens-before (3): a send happens-before its receive completes")
+	fmt.Fprintln(out, "  (3) happens-before (4): program order again")
+	fmt.Fprintln(out, "  So (1) happens-before (4): the read is guaranteed to see the write,")
+	fmt.Fprintln(out, "  even though sharedMessage itself is a plain, unsynchronized variable")
+}
+
+// The ownership-transfer pipeline: a few buffers circulating for many messages
+const (
+	transferMessages   = 1000
+	transferBufferSize = 4 << 10
+	transferBuffers    = 2
+)
+
+// transferOwnership moves buffers from a producer to a consumer and back.
+// Whoever last received a buffer owns it; the one rule - don't touch a
+// buffer after sending it - is a convention the compiler doesn't check.
+// It returns the sum of every byte the consumer read.
+func transferOwnership(messages int) int {
+	free := make(chan []byte, transferBuffers) // consumer -> producer
+	full := make(chan []byte)                  // producer -> consumer
+	for range transferBuffers {
+		free <- make([]byte, transferBufferSize)
+	}
+
+	total := make(chan int)
+	go func() {
+		sum := 0
+		for buf := range full { // receive: the consumer owns buf
+			for _, b := range buf {
+				sum += int(b)
+			}
+			free <- buf // send: ownership goes back to the producer
+		}
+		total <- sum
+	}()
+
+	for i := range messages {
+		buf := <-free // receive: the producer owns buf
+		for j := range buf {
+			buf[j] = byte(i)
+		}
+		full <- buf // send: from here on, buf belongs to the consumer
+		// buf[0] = 0 here would compile - and be a data race
+	}
+	close(full)
+	return <-total
+}
+
+// copyPerMessage is the pipeline without reuse: a fresh buffer every message
+func copyPerMessage(messages int) int {
+	full := make(chan []byte)
+	total := make(chan int)
+	go func() {
+		sum := 0
+		for buf := range full {
+			for _, b := range buf {
+				sum += int(b)
+			}
+		}
+		total <- sum
+	}()
+	for i := range messages {
+		buf := make([]byte, transferBufferSize)
+		for j := range buf {
+			buf[j] = byte(i)
+		}
+		full <- buf
+	}
+	close(full)
+	return <-total
+}
+
+// Demonstrate ownership transfer: the idiomatic Go analogue of a Rust move
+func DemonstrateOwnershipTransfer() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "OWNERSHIP TRANSFER THROUGH CHANNELS")
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintf(out, "  %d messages of %d bytes, producer -> consumer\n", transferMessages, transferBufferSize)
+
+	var recycled, fresh int
+	TrackMemory(fmt.Sprintf("%d buffers handed back and forth", transferBuffers), func() {
+		recycled = transferOwnership(transferMessages)
+	})
+	TrackMemory("a fresh buffer per message", func() {
+		fresh = copyPerMessage(transferMessages)
+	})
+	fmt.Fprintf(out, "\n  Checksums: %d and %d (same data either way)\n", recycled, fresh)
+
+	fmt.Fprintln(out, "\n  \"Do not communicate by sharing memory; share memory by communicating.\"")
+	fmt.Fprintln(out, "  Sending a []byte copies only its header - both sides could still reach")
+	fmt.Fprintln(out, "  the array. What makes it a transfer is the convention that the sender")
+	fmt.Fprintln(out, "  stops using it, and the send happening-before the receive makes the")
+	fmt.Fprintln(out, "  producer's writes visible to the consumer. Break the convention and the")
+	fmt.Fprintln(out, "  program still compiles; only -race notices.")
+	fmt.Fprintln(out, "  In Rust, tx.send(buf) moves buf: using it afterwards is a compile error.")
+}
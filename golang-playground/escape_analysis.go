@@ -1,8 +1,29 @@
 package main
 
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
 // This file demonstrates Go's escape analysis
 // Run with: go build -gcflags="-m" to see escape analysis
 
+func init() {
+	Register(newDemo("escape", "Run the escape analysis catalog (see make escape)", DemonstrateEscapeAnalysis).in(categoryEscape))
+	Register(newDemo("value-vs-pointer", "Returning User by value vs *User: allocs per call", DemonstrateValueVsPointerReturn).in(categoryEscape))
+	Register(newDemo("fmt-escape", "Passing an int to fmt boxes it on the heap", DemonstrateFmtEscape).in(categoryEscape))
+	Register(newDemo("channel-escape", "What sending a pointer or value on a channel allocates", DemonstrateChannelEscape).in(categoryEscape))
+	Register(newDemo("inlining-effect", "The same pointer-returning helper, inlined vs //go:noinline", DemonstrateInliningEffect).in(categoryEscape))
+	Register(newDemo("array-escape", "Large local arrays move to the heap past 128KB", DemonstrateArrayEscape).in(categoryEscape))
+	Register(newDemo("loop-var-address", "&i in a loop: per-iteration (Go 1.22+) vs shared variable", DemonstrateLoopVarAddress).in(categoryEscape))
+	Register(newDemo("closure-capture", "A closure capturing a whole LargeObject vs just its ID", DemonstrateClosureCapture).in(categoryEscape))
+	Register(newDemo("interface-return", "Returning User as an interface boxes it; returning User doesn't", DemonstrateInterfaceReturn).in(categoryEscape))
+	Register(newDemo("method-value", "f := u.Method binds a copy of u - and can allocate", DemonstrateMethodValue).in(categoryEscape))
+	Register(newDemo("new-vs-literal", "new(User) vs &User{}: usage, not syntax, decides the heap", DemonstrateNewVsLiteral).in(categoryEscape))
+	Register(newDemo("slice-of-pointers", "Appending &local to a []*User moves every local to the heap", DemonstrateSliceOfPointers).in(categoryEscape))
+}
+
 // Example 1: Does NOT escape - stays on stack
 func noEscape() {
 	x := 42
@@ -55,6 +76,281 @@ func escapesViaClosure() func() int {
 	}
 }
 
+// Example 8: ESCAPES - deferred closure capturing a local
+var deferSink int
+
+func escapesViaDefer() {
+	for i := 0; i < 3; i++ {
+		x := i
+		// -m reports: "func literal escapes to heap"
+		// Defers in a loop can't be open-coded onto the stack - the runtime
+		// keeps a heap-allocated defer record, and the captured x goes with it
+		defer func() { deferSink += x }()
+	}
+}
+
+func recordDefer(v int) {
+	deferSink = v
+}
+
+// Example 9: Does NOT escape - deferred direct call
+func noEscapeDeferDirect() {
+	x := 42
+	// Arguments of a deferred call are evaluated now and copied into the
+	// (stack) defer record - nothing is captured, so nothing escapes
+	defer recordDefer(x)
+}
+
+// Example 10: ESCAPES - passed to fmt as ...interface{}
+var (
+	fmtInput  = 7          // package-level so the compiler can't constant-fold x
+	fmtWriter = io.Discard // same escape as os.Stdout, without flooding the terminal
+	fmtSink   int
+)
+
+func escapesViaFmt() {
+	x := fmtInput * 1000
+	// -m reports: "x escapes to heap" - Fprintln takes ...interface{}, so x
+	// is boxed, and fmt hands the box to reflection the compiler can't see through
+	fmt.Fprintln(fmtWriter, x)
+}
+
+// Example 11: Does NOT escape - same computation, never printed
+func noEscapeWithoutFmt() {
+	x := fmtInput * 1000
+	fmtSink = x // copying an int is not boxing it
+}
+
+// Example 12: ESCAPES - sent on a channel
+var channelSink int
+
+func escapesViaChannel() {
+	ch := make(chan *User, 1) // -m: channels are always allocated by the runtime (makechan)
+	u := &User{Name: "Carol", Age: 40}
+	// -m reports: "&User{...} escapes to heap" - whoever receives may run
+	// on another goroutine and outlive this frame, so the compiler gives up
+	ch <- u
+	got := <-ch
+	channelSink = got.Age
+}
+
+// Sending the value instead copies it INTO the channel's (heap) buffer;
+// the local itself doesn't escape, but the channel still costs allocations
+func copiesViaChannel() {
+	ch := make(chan User, 1)
+	u := User{Name: "Carol", Age: 40}
+	ch <- u
+	got := <-ch
+	channelSink = got.Age
+}
+
+// Example 13: Does NOT escape - same struct, no channel involved
+func noEscapeWithoutChannel() {
+	u := User{Name: "Carol", Age: 40}
+	channelSink = u.Age
+}
+
+// Example 14: ESCAPES - address of a loop variable stored in a slice
+var loopPtrSink []*int
+
+const loopIterations = 3
+
+func escapesViaLoopVarAddress() {
+	ptrs := make([]*int, 0, loopIterations)
+	for i := 0; i < loopIterations; i++ {
+		// -m reports: "moved to heap: i" - since Go 1.22 each iteration has
+		// its own i, so each &i is a separate heap variable
+		ptrs = append(ptrs, &i)
+	}
+	loopPtrSink = ptrs
+}
+
+// The pre-Go-1.22 behavior, spelled out: ONE variable shared by every iteration
+func sharedLoopVarAddress() {
+	ptrs := make([]*int, 0, loopIterations)
+	var i int // -m reports: "moved to heap: i" - but only once
+	for i = 0; i < loopIterations; i++ {
+		ptrs = append(ptrs, &i)
+	}
+	loopPtrSink = ptrs
+}
+
+// Example 15: ESCAPES - local array too large for the stack
+// Unlike Example 4 (make, an implicit allocation limited to 64KB), an explicitly
+// declared variable may use the stack up to 128KB (the compiler's MaxStackVarSize)
+var arraySink byte
+
+func escapesViaLargeArray() {
+	var a [1 << 20]byte // -m reports: "moved to heap: a" (1MB > 128KB)
+	a[arraySink] = 1
+	arraySink = a[len(a)-1-int(arraySink)]
+}
+
+// Exactly at the limit - stays on the stack
+func noEscapeArrayAtLimit() {
+	var a [128 << 10]byte // no diagnostic: 128KB fits
+	a[arraySink] = 1
+	arraySink = a[len(a)-1-int(arraySink)]
+}
+
+// One byte over the limit - moved to the heap
+func escapesViaArrayOverLimit() {
+	var a [128<<10 + 1]byte // -m reports: "moved to heap: a"
+	a[arraySink] = 1
+	arraySink = a[len(a)-1-int(arraySink)]
+}
+
+// Example 16: Does NOT escape - small local array
+func noEscapeSmallArray() {
+	var a [1024]byte // no diagnostic: lives in the frame
+	a[arraySink] = 1
+	arraySink = a[len(a)-1-int(arraySink)]
+}
+
+// Example 17: Inlining decides whether a returned pointer escapes
+var inlineSink int
+
+// Inlinable: once its body is pasted into the caller, x is just a caller local
+func newCounterInlinable() *int {
+	x := 0
+	return &x // -m: "moved to heap: x" for the standalone copy only
+}
+
+// Same body, but the compiler must analyze it as a real call
+//
+//go:noinline
+func newCounterNoInline() *int {
+	x := 0
+	return &x // -m: "moved to heap: x" - every call allocates
+}
+
+// -m: "inlining call to newCounterInlinable" and no escape for the inlined x
+func useInlinedCounter() {
+	p := newCounterInlinable()
+	*p++
+	inlineSink = *p
+}
+
+// The pointer never leaves this function either - but the callee can't know that
+func useNoInlineCounter() {
+	p := newCounterNoInline()
+	*p++
+	inlineSink = *p
+}
+
+// Example 18: ESCAPES - &local stored in a []*User that outlives the function
+var (
+	userPtrSliceSink []*User
+	userSliceSink    []User
+	userAgeSink      int
+)
+
+const sliceOfPointersUsers = 8
+
+func escapesViaSliceOfPointers() {
+	users := make([]*User, 0, sliceOfPointersUsers) // -m: "make([]*User, 0, 8) escapes to heap"
+	for i := 0; i < sliceOfPointersUsers; i++ {
+		u := User{Name: "user", Age: i} // -m: "moved to heap: u" - once per iteration
+		users = append(users, &u)
+	}
+	userPtrSliceSink = users
+}
+
+// The slice escapes, but it holds copies - one allocation for the backing array
+func copiesIntoEscapingSlice() {
+	users := make([]User, 0, sliceOfPointersUsers) // -m: "make([]User, 0, 8) escapes to heap"
+	for i := 0; i < sliceOfPointersUsers; i++ {
+		u := User{Name: "user", Age: i} // no diagnostic: u is copied into the slice
+		users = append(users, u)
+	}
+	userSliceSink = users
+}
+
+// Neither the slice nor its elements leave - everything stays in the frame
+func noEscapeSliceOfValues() {
+	users := make([]User, 0, sliceOfPointersUsers) // -m: "make([]User, 0, 8) does not escape"
+	for i := 0; i < sliceOfPointersUsers; i++ {
+		users = append(users, User{Name: "user", Age: i})
+	}
+	total := 0
+	for _, u := range users {
+		total += u.Age
+	}
+	userAgeSink = total
+}
+
+// Example 19: ESCAPES - concrete value returned as an interface
+type describer interface{ Describe() string }
+
+// Kept out of line, like a constructor behind a package boundary: the caller
+// can't be analyzed together with it. The fields come in as arguments because
+// a User built only from constants is boxed into read-only static data instead
+//
+//go:noinline
+func escapesViaInterfaceReturn(name string, age int) describer {
+	u := User{Name: name, Age: age}
+	return u // -m: "u escapes to heap" - the conversion boxes a copy of u
+}
+
+// Same value, concrete return type - copied out in registers, nothing boxed
+//
+//go:noinline
+func noEscapeConcreteReturn(name string, age int) User {
+	u := User{Name: name, Age: age}
+	return u // no diagnostic
+}
+
+// Example 20: ESCAPES - a method value stored beyond the call
+var (
+	methodValueSink  func() int
+	methodResultSink int
+)
+
+// u.ageByValue is a closure over a copy of u; storing it moves that closure
+// (and the copy) to the heap
+func escapesViaMethodValue(name string, age int) {
+	u := User{Name: name, Age: age}
+	methodValueSink = u.ageByValue // -m: "u.ageByValue escapes to heap"
+}
+
+// The same method value, called and dropped - the closure stays in the frame
+func noEscapeLocalMethodValue(name string, age int) {
+	u := User{Name: name, Age: age}
+	f := u.ageByValue // -m: "u.ageByValue does not escape"
+	methodResultSink = f()
+}
+
+// A direct call binds nothing: the receiver is just the first argument
+func noEscapeDirectMethodCall(name string, age int) {
+	u := User{Name: name, Age: age}
+	methodResultSink = u.ageByValue()
+}
+
+// Example 21: new(T) and &T{} escape (or don't) for the same reasons
+//
+//go:noinline
+func escapesViaNew(name string, age int) *User {
+	u := new(User) // -m: "new(User) escapes to heap"
+	u.Name, u.Age = name, age
+	return u
+}
+
+//go:noinline
+func escapesViaLiteralPointer(name string, age int) *User {
+	return &User{Name: name, Age: age} // -m: "&User{...} escapes to heap"
+}
+
+func noEscapeNew(name string, age int) {
+	u := new(User) // -m: "new(User) does not escape"
+	u.Name, u.Age = name, age
+	userAgeSink = u.Age
+}
+
+func noEscapeLiteralPointer(name string, age int) {
+	u := &User{Name: name, Age: age} // -m: "&User{...} does not escape"
+	userAgeSink = u.Age
+}
+
 // Helper function to demonstrate escape analysis
 func DemonstrateEscapeAnalysis() {
 	// Run: go build -gcflags="-m" to see escape analysis output
@@ -70,4 +366,375 @@ func DemonstrateEscapeAnalysis() {
 
 	fn := escapesViaClosure()
 	_ = fn()
+
+	escapesViaDefer()
+	noEscapeDeferDirect()
+
+	escapesViaFmt()
+	noEscapeWithoutFmt()
+
+	escapesViaChannel()
+	copiesViaChannel()
+	noEscapeWithoutChannel()
+
+	escapesViaLoopVarAddress()
+	sharedLoopVarAddress()
+
+	escapesViaLargeArray()
+	noEscapeArrayAtLimit()
+	escapesViaArrayOverLimit()
+	noEscapeSmallArray()
+
+	useInlinedCounter()
+	useNoInlineCounter()
+
+	escapesViaSliceOfPointers()
+	copiesIntoEscapingSlice()
+	noEscapeSliceOfValues()
+
+	_ = escapesViaInterfaceReturn("Dave", 35)
+	_ = noEscapeConcreteReturn("Dave", 35)
+
+	escapesViaMethodValue("Erin", 28)
+	noEscapeLocalMethodValue("Erin", 28)
+	noEscapeDirectMethodCall("Erin", 28)
+
+	_ = escapesViaNew("Frank", 50)
+	_ = escapesViaLiteralPointer("Frank", 50)
+	noEscapeNew("Frank", 50)
+	noEscapeLiteralPointer("Frank", 50)
+
+	// The first examples have no demo of their own - measure them here
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ESCAPE ANALYSIS CATALOG")
+	fmt.Fprintln(out, "============================================================")
+	catalog := []struct {
+		name string
+		fn   func()
+	}{
+		{"noEscape", noEscape},
+		{"escapesViaReturn", func() { globalPtr = escapesViaReturn() }},
+		{"escapesViaInterface", escapesViaInterface},
+		{"escapesViaSizeTooLarge", escapesViaSizeTooLarge},
+		{"noEscapeLocalPointer", noEscapeLocalPointer},
+		{"escapesViaGlobal", escapesViaGlobal},
+		{"escapesViaClosure", func() { catalogClosureSink = escapesViaClosure() }},
+		{"escapesViaDefer", escapesViaDefer},
+		{"noEscapeDeferDirect", noEscapeDeferDirect},
+	}
+	for _, c := range catalog {
+		fmt.Fprintf(out, "  %-24s %.0f allocs/op\n", c.name, MeasureAllocs(c.name, c.fn))
+	}
+	fmt.Fprintln(out, "  (escapesViaInterface boxes the constant 42 - constants and small ints are")
+	fmt.Fprintln(out, "  boxed from static data, so it escapes without allocating)")
+	fmt.Fprintln(out, "  For the compiler's reasoning: go build -gcflags=\"-m\" .")
+}
+
+// Keeps escapesViaClosure's result alive while the catalog measures it
+var catalogClosureSink func() int
+
+// Sinks that outlive the constructors below
+var (
+	userValueSink User
+	userPtrSink   *User
+)
+
+// Returns a copy - the User lives in the caller's frame (or the sink)
+func newUserValue(name string, age int) User {
+	return User{Name: name, Age: age}
+}
+
+// Returns a pointer - the User must outlive this frame, so it goes to the heap
+func newUserPointer(name string, age int) *User {
+	return &User{Name: name, Age: age}
+}
+
+// Demonstrate that returning a small struct by value can beat returning a pointer
+func DemonstrateValueVsPointerReturn() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "RETURN BY VALUE VS RETURN BY POINTER")
+	fmt.Fprintln(out, "============================================================")
+
+	valueAllocs := MeasureAllocs("newUserValue", func() {
+		userValueSink = newUserValue("Alice", 30)
+	})
+	pointerAllocs := MeasureAllocs("newUserPointer", func() {
+		userPtrSink = newUserPointer("Alice", 30)
+	})
+
+	fmt.Fprintf(out, "  newUserValue   (returns User):  %.0f allocs/op\n", valueAllocs)
+	fmt.Fprintf(out, "  newUserPointer (returns *User): %.0f allocs/op\n", pointerAllocs)
+	fmt.Fprintln(out, "\n  A User is a string header + an int (24 bytes) - copying it is a few")
+	fmt.Fprintln(out, "  register moves, while the pointer version pays for malloc now and GC later")
+	fmt.Fprintln(out, "  Benchmark it: go test -bench=UserReturn -benchmem")
+}
+
+// Demonstrate the allocation hiding inside "just a print"
+func DemonstrateFmtEscape() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ESCAPE VIA FMT")
+	fmt.Fprintln(out, "============================================================")
+
+	fmt.Fprintf(out, "  fmt.Fprintln(w, x):  %.0f allocs/op (allocated: %t)\n", MeasureAllocs("escapesViaFmt", escapesViaFmt), DidAllocate(escapesViaFmt))
+	fmt.Fprintf(out, "  sink = x (no print): %.0f allocs/op (allocated: %t)\n", MeasureAllocs("noEscapeWithoutFmt", noEscapeWithoutFmt), DidAllocate(noEscapeWithoutFmt))
+	fmt.Fprintln(out, "\n  Every fmt argument is converted to interface{} - a plain int gets boxed")
+	fmt.Fprintln(out, "  on the heap (the small-int cache only covers 0..255)")
+	fmt.Fprintln(out, "  This is why hot loops shouldn't log: each call allocates, even when")
+	fmt.Fprintln(out, "  the output goes nowhere")
+}
+
+// Demonstrate what a channel send costs compared to keeping the value local
+func DemonstrateChannelEscape() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ESCAPE VIA CHANNEL")
+	fmt.Fprintln(out, "============================================================")
+
+	fmt.Fprintf(out, "  send *User on chan:  %.0f allocs/op (channel, buffer, User)\n", MeasureAllocs("escapesViaChannel", escapesViaChannel))
+	fmt.Fprintf(out, "  send User on chan:   %.0f allocs/op (channel, buffer)\n", MeasureAllocs("copiesViaChannel", copiesViaChannel))
+	fmt.Fprintf(out, "  no channel:          %.0f allocs/op\n", MeasureAllocs("noEscapeWithoutChannel", noEscapeWithoutChannel))
+	fmt.Fprintln(out, "\n  A channel connects goroutines, so the compiler assumes anything reachable")
+	fmt.Fprintln(out, "  through a sent pointer may be used after the sender returns - it escapes")
+	fmt.Fprintln(out, "  In Rust, sending moves ownership; in Go, the GC keeps it alive for both sides")
+}
+
+// Print where the collected loop pointers point and what they read
+func printLoopPointers(label string) {
+	addresses := make([]string, len(loopPtrSink))
+	values := make([]int, len(loopPtrSink))
+	for i, p := range loopPtrSink {
+		addresses[i] = fmt.Sprintf("%p", p)
+		values[i] = *p
+	}
+	fmt.Fprintf(out, "  %s\n", label)
+	fmt.Fprintf(out, "    pointers: %v\n", addresses)
+	fmt.Fprintf(out, "    values:   %v\n", values)
+}
+
+// Demonstrate taking &i in a loop - the footgun Go 1.22 changed
+func DemonstrateLoopVarAddress() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ADDRESS OF A LOOP VARIABLE")
+	fmt.Fprintln(out, "============================================================")
+
+	perIteration := TrackMemory("for i := ...; append(ptrs, &i) (Go 1.22+)", func() {
+		escapesViaLoopVarAddress()
+	})
+	printLoopPointers("Per-iteration i (this module's go.mod is >= 1.22):")
+
+	shared := TrackMemory("var i; for i = ...; append(ptrs, &i) (pre-1.22)", func() {
+		sharedLoopVarAddress()
+	})
+	printLoopPointers("One shared i (what every loop did before Go 1.22):")
+
+	fmt.Fprintf(out, "\n  Mallocs: %d per-iteration vs %d shared (each includes the ptrs slice)\n",
+		perIteration.Mallocs, shared.Mallocs)
+	fmt.Fprintln(out, "  Before Go 1.22 every &i aliased one variable, so all pointers read the")
+	fmt.Fprintln(out, "  final value - closures and goroutines in loops hit the same bug.")
+	fmt.Fprintln(out, "  Go 1.22+ gives each iteration a fresh i: correct, but one heap var per &i.")
+	loopPtrSink = nil
+}
+
+// Demonstrate the size threshold for explicitly declared local arrays
+func DemonstrateArrayEscape() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "LARGE LOCAL ARRAYS")
+	fmt.Fprintln(out, "============================================================")
+
+	cases := []struct {
+		decl string
+		fn   func()
+	}{
+		{"var a [1024]byte", noEscapeSmallArray},
+		{"var a [128 << 10]byte", noEscapeArrayAtLimit},
+		{"var a [128<<10 + 1]byte", escapesViaArrayOverLimit},
+		{"var a [1 << 20]byte", escapesViaLargeArray},
+	}
+	for _, c := range cases {
+		fmt.Fprintf(out, "  %-25s heap allocated: %t\n", c.decl, MeasureAllocs(c.decl, c.fn) > 0)
+	}
+
+	fmt.Fprintln(out, "\n  The compiler keeps an explicitly declared variable on the stack up to")
+	fmt.Fprintln(out, "  128KB; past that it reports \"moved to heap: a\" and allocates it.")
+	fmt.Fprintln(out, "  Implicit allocations (make, new, &T{}) have a lower 64KB limit -")
+	fmt.Fprintln(out, "  escapesViaSizeTooLarge mixes in that second mechanism.")
+}
+
+// Demonstrate how inlining changes the escape analysis verdict
+func DemonstrateInliningEffect() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "INLINING AND ESCAPE ANALYSIS")
+	fmt.Fprintln(out, "============================================================")
+
+	fmt.Fprintf(out, "  caller of newCounterInlinable: %.0f allocs/op\n", MeasureAllocs("useInlinedCounter", useInlinedCounter))
+	fmt.Fprintf(out, "  caller of newCounterNoInline:  %.0f allocs/op\n", MeasureAllocs("useNoInlineCounter", useNoInlineCounter))
+
+	fmt.Fprintln(out, "\n  Escape analysis works one function at a time. A function returning &x")
+	fmt.Fprintln(out, "  must put x on the heap - it can't see what callers do with the pointer.")
+	fmt.Fprintln(out, "  Inlining runs first: it copies the callee's body into the caller, where")
+	fmt.Fprintln(out, "  the compiler CAN see the pointer never leaves, so x stays on the stack.")
+	fmt.Fprintln(out, "  Small constructors returning pointers are often free for exactly this reason.")
+	fmt.Fprintln(out, "  See both verdicts with: go build -gcflags=\"-m\" .")
+}
+
+// Demonstrate that storing addresses, not the slice, is what moves each element
+func DemonstrateSliceOfPointers() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ESCAPE VIA A SLICE OF POINTERS")
+	fmt.Fprintln(out, "============================================================")
+
+	n := sliceOfPointersUsers
+	fmt.Fprintf(out, "  %d users into an escaping []*User: %.0f allocs/op (slice + one per &u)\n", n, MeasureAllocs("escapesViaSliceOfPointers", escapesViaSliceOfPointers))
+	fmt.Fprintf(out, "  %d users into an escaping []User:  %.0f allocs/op (the backing array)\n", n, MeasureAllocs("copiesIntoEscapingSlice", copiesIntoEscapingSlice))
+	fmt.Fprintf(out, "  %d users into a local []User:      %.0f allocs/op\n", n, MeasureAllocs("noEscapeSliceOfValues", noEscapeSliceOfValues))
+
+	fmt.Fprintln(out, "\n  Once &u is stored somewhere that outlives the loop, u can't live in the")
+	fmt.Fprintln(out, "  frame - -m reports \"moved to heap: u\" and every iteration pays a malloc.")
+	fmt.Fprintln(out, "  A []User holds copies: only the backing array escapes with the slice.")
+	fmt.Fprintln(out, "  This is why heapAllocationViaPointer allocates per object. Check with:")
+	fmt.Fprintln(out, "  go build -gcflags=\"-m\" . 2>&1 | grep escape_analysis.go")
+	userPtrSliceSink, userSliceSink = nil, nil
+}
+
+// Closures built by DemonstrateClosureCapture, held so they stay reachable
+var closureSink []func() int
+
+const capturedClosures = 1000
+
+// Captures all of o: the closure copies the 32-byte struct, and its Data
+// header keeps the 1KB buffer reachable for as long as the closure lives
+func captureWholeObject(o LargeObject) func() int {
+	return func() int { return o.ID }
+}
+
+// Copies out the one field it needs: an 8-byte capture, no buffer retained
+func captureIDOnly(o LargeObject) func() int {
+	id := o.ID
+	return func() int { return id }
+}
+
+// Build n closures, each over a fresh LargeObject nothing else references
+func buildClosures(capture func(LargeObject) func() int) {
+	closureSink = make([]func() int, capturedClosures)
+	for i := range closureSink {
+		closureSink[i] = capture(LargeObject{ID: i, Data: make([]byte, 1024)})
+	}
+}
+
+// liveHeap reports HeapAlloc after a GC, i.e. only what is still reachable
+func liveHeap() uint64 {
+	var m memSnapshot
+	runtime.GC()
+	readSnapshot(&m)
+	return m.HeapAlloc
+}
+
+// retainedSince is how far the live heap has grown past base, zero if a
+// collection in between left it smaller
+func retainedSince(base uint64) uint64 {
+	live := liveHeap()
+	return live - min(base, live)
+}
+
+// Demonstrate that what a closure captures decides what it keeps alive
+func DemonstrateClosureCapture() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "CLOSURE CAPTURE SIZE")
+	fmt.Fprintln(out, "============================================================")
+
+	closureSink = nil
+	base := liveHeap()
+
+	whole := TrackMemory(fmt.Sprintf("%d closures capturing a whole LargeObject", capturedClosures), func() {
+		buildClosures(captureWholeObject)
+	})
+	wholeRetained := retainedSince(base)
+
+	closureSink = nil
+	base = liveHeap()
+	idOnly := TrackMemory(fmt.Sprintf("%d closures capturing only the ID", capturedClosures), func() {
+		buildClosures(captureIDOnly)
+	})
+	idRetained := retainedSince(base)
+
+	fmt.Fprintf(out, "\n  %-22s %8s %14s\n", "", "allocated", "retained")
+	fmt.Fprintf(out, "  %-22s %8d B %12d B\n", "whole LargeObject", whole.TotalAlloc, wholeRetained)
+	fmt.Fprintf(out, "  %-22s %8d B %12d B\n", "ID only", idOnly.TotalAlloc, idRetained)
+
+	fmt.Fprintln(out, "\n  A returned closure is a heap object holding its captures. Capturing o")
+	fmt.Fprintln(out, "  copies the whole struct into it - and its Data slice pins the 1KB buffer,")
+	fmt.Fprintln(out, "  so every buffer survives GC. Copy the field you need into a local first")
+	fmt.Fprintln(out, "  and the closure shrinks to a function pointer plus one int.")
+	fmt.Fprintln(out, "  Rust makes this explicit: move |..| takes exactly the captured values.")
+	closureSink = nil
+}
+
+// Demonstrate the allocation cost of "accept interfaces, return structs"
+func DemonstrateInterfaceReturn() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "RETURNING AN INTERFACE VS A CONCRETE TYPE")
+	fmt.Fprintln(out, "============================================================")
+
+	asInterface := func() { _ = escapesViaInterfaceReturn("Dave", 35) }
+	asConcrete := func() { _ = noEscapeConcreteReturn("Dave", 35) }
+	fmt.Fprintf(out, "  func() describer { return u }: allocated %t (%.0f allocs/op)\n", DidAllocate(asInterface), MeasureAllocs("escapesViaInterfaceReturn", asInterface))
+	fmt.Fprintf(out, "  func() User      { return u }: allocated %t (%.0f allocs/op)\n", DidAllocate(asConcrete), MeasureAllocs("noEscapeConcreteReturn", asConcrete))
+
+	fmt.Fprintln(out, "\n  An interface value is a (type, pointer) pair, so returning a User as")
+	fmt.Fprintln(out, "  describer copies it to the heap for the pointer to point at - unless the")
+	fmt.Fprintln(out, "  call is inlined and the compiler sees the box never leaves the caller.")
+	fmt.Fprintln(out, "  The concrete return is a plain copy. Hence the Go proverb: accept")
+	fmt.Fprintln(out, "  interfaces, return structs. Rust's -> impl Trait is static dispatch and")
+	fmt.Fprintln(out, "  never boxes; only -> Box<dyn Trait> pays this allocation.")
+}
+
+// Demonstrate that a method value is a closure over its receiver
+func DemonstrateMethodValue() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "METHOD VALUES CAPTURE THE RECEIVER")
+	fmt.Fprintln(out, "============================================================")
+
+	cases := []struct {
+		code string
+		fn   func()
+	}{
+		{"sink = u.ageByValue()", func() { noEscapeDirectMethodCall("Erin", 28) }},
+		{"f := u.ageByValue; sink = f()", func() { noEscapeLocalMethodValue("Erin", 28) }},
+		{"methodValueSink = u.ageByValue", func() { escapesViaMethodValue("Erin", 28) }},
+	}
+	for _, c := range cases {
+		fmt.Fprintf(out, "  %-32s allocated: %-5t (%.0f allocs/op)\n", c.code, DidAllocate(c.fn), MeasureAllocs(c.code, c.fn))
+	}
+	methodValueSink = nil
+
+	fmt.Fprintln(out, "\n  f := u.Describe evaluates u right away and packages a copy of it with")
+	fmt.Fprintln(out, "  the method - exactly a closure func() string { return copy.Describe() }.")
+	fmt.Fprintln(out, "  Used locally the closure lives on the stack; once it is stored, passed to")
+	fmt.Fprintln(out, "  a callback API or started as a goroutine, it and the receiver copy go to")
+	fmt.Fprintln(out, "  the heap. Calling u.Describe() directly never builds the closure at all.")
+}
+
+// Demonstrate that new and & on a composite literal are the same allocation
+func DemonstrateNewVsLiteral() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "new(User) VS &User{}")
+	fmt.Fprintln(out, "============================================================")
+
+	cases := []struct {
+		code string
+		fn   func()
+	}{
+		{"return new(User)", func() { userPtrSink = escapesViaNew("Frank", 50) }},
+		{"return &User{...}", func() { userPtrSink = escapesViaLiteralPointer("Frank", 50) }},
+		{"u := new(User), used locally", func() { noEscapeNew("Frank", 50) }},
+		{"u := &User{...}, used locally", func() { noEscapeLiteralPointer("Frank", 50) }},
+	}
+	for _, c := range cases {
+		fmt.Fprintf(out, "  %-30s allocated: %-5t (%.0f allocs/op)\n", c.code, DidAllocate(c.fn), MeasureAllocs(c.code, c.fn))
+	}
+	userPtrSink = nil
+
+	fmt.Fprintln(out, "\n  Both forms just ask for a *User. Escape analysis looks at where the pointer")
+	fmt.Fprintln(out, "  goes, never at how it was spelled: returned, both reach the heap; kept")
+	fmt.Fprintln(out, "  local, both stay in the frame. Unlike C++'s new or Rust's Box::new, Go's")
+	fmt.Fprintln(out, "  new does not mean \"heap\" - for Go the heap is an implementation detail.")
 }
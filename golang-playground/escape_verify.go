@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// This file asks the compiler itself which escape examples escape, instead
+// of taking the comments in escape_analysis.go on trust
+
+func init() {
+	Register(demo{
+		name:        "escape-verify",
+		description: "Check the escape catalog against go build -gcflags='-m -m'",
+		category:    categoryEscape,
+		run:         DemonstrateEscapeVerifier,
+	})
+}
+
+// The catalog file, and how long the compiler may take to analyze it
+const (
+	escapeCatalogFile  = "escape_analysis.go"
+	escapeBuildTimeout = 2 * time.Minute
+)
+
+// Catalog functions document their behavior in their name
+const (
+	escapingPrefix = "escapesVia"
+	stayingPrefix  = "noEscape"
+)
+
+// escapeSubjects names what each catalog function's verdict is about, as
+// the compiler prints it: a variable ("moved to heap: x") or an expression
+// ("new(User) escapes to heap"). Diagnostics about anything else in the
+// function - a slice holding the subject, a callee's argument - don't count.
+var escapeSubjects = map[string]string{
+	"noEscape":                  "x",
+	"escapesViaReturn":          "x",
+	"escapesViaInterface":       "x",
+	"escapesViaSizeTooLarge":    "make([]int, 1000000)",
+	"noEscapeLocalPointer":      "x",
+	"escapesViaGlobal":          "x",
+	"escapesViaClosure":         "func literal",
+	"escapesViaDefer":           "func literal",
+	"noEscapeDeferDirect":       "x",
+	"escapesViaFmt":             "x",
+	"noEscapeWithoutFmt":        "x",
+	"escapesViaChannel":         "&User{...}",
+	"noEscapeWithoutChannel":    "u",
+	"escapesViaLoopVarAddress":  "i",
+	"escapesViaLargeArray":      "a",
+	"noEscapeArrayAtLimit":      "a",
+	"escapesViaArrayOverLimit":  "a",
+	"noEscapeSmallArray":        "a",
+	"escapesViaSliceOfPointers": "u",
+	"noEscapeSliceOfValues":     "make([]User, 0, 8)",
+	"escapesViaInterfaceReturn": "u",
+	"noEscapeConcreteReturn":    "u",
+	"escapesViaMethodValue":     "u.ageByValue",
+	"noEscapeLocalMethodValue":  "u.ageByValue",
+	"noEscapeDirectMethodCall":  "u",
+	"escapesViaNew":             "new(User)",
+	"escapesViaLiteralPointer":  "&User{...}",
+	"noEscapeNew":               "new(User)",
+	"noEscapeLiteralPointer":    "&User{...}",
+}
+
+// funcSpan is the line range of one top-level function
+type funcSpan struct {
+	name       string
+	start, end int
+}
+
+// escapeVerdict compares a function's documented behavior with the compiler's
+type escapeVerdict struct {
+	name        string
+	subject     string
+	expected    bool // escapesVia* documents an escape, noEscape* documents none
+	reported    bool
+	diagnostics []string // about the subject only
+}
+
+// catalogFunctions parses path and returns the escapesVia*/noEscape* functions
+func catalogFunctions(path string) ([]funcSpan, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var spans []funcSpan
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		name := fn.Name.Name
+		if strings.HasPrefix(name, escapingPrefix) || strings.HasPrefix(name, stayingPrefix) {
+			spans = append(spans, funcSpan{
+				name:  name,
+				start: fset.Position(fn.Pos()).Line,
+				end:   fset.Position(fn.End()).Line,
+			})
+		}
+	}
+	return spans, nil
+}
+
+// compilerEscapes runs the escape analysis on the package in dir and returns
+// the "escapes to heap" / "moved to heap" diagnostics for file, by line
+func compilerEscapes(dir, file string) (map[int][]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), escapeBuildTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "go", "build", "-gcflags=-m -m", "-o", os.DevNull, ".")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go build -gcflags='-m -m': %w\n%s", err, stderr.String())
+	}
+
+	escapes := make(map[int][]string)
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		// ./escape_analysis.go:31:9: moved to heap: x
+		parts := strings.SplitN(scanner.Text(), ":", 4)
+		if len(parts) < 4 || filepath.Base(parts[0]) != file {
+			continue
+		}
+		msg := strings.TrimSpace(parts[3])
+		if !strings.Contains(msg, "escapes to heap") && !strings.HasPrefix(msg, "moved to heap:") {
+			continue
+		}
+		line, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		escapes[line] = append(escapes[line], strings.TrimSuffix(msg, ":"))
+	}
+	return escapes, scanner.Err()
+}
+
+// diagnosticSubject is what an escape diagnostic is about: S in
+// "moved to heap: S", "S escapes to heap" and "S escapes to heap in fn"
+func diagnosticSubject(msg string) string {
+	if subject, ok := strings.CutPrefix(msg, "moved to heap: "); ok {
+		return subject
+	}
+	subject, _, _ := strings.Cut(msg, " escapes to heap")
+	return subject
+}
+
+// verifyEscapes matches each catalog function with the diagnostics inside
+// it about its subject in escapeSubjects
+func verifyEscapes(spans []funcSpan, escapes map[int][]string) ([]escapeVerdict, error) {
+	verdicts := make([]escapeVerdict, 0, len(spans))
+	for _, span := range spans {
+		subject, ok := escapeSubjects[span.name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no entry in escapeSubjects", span.name)
+		}
+		v := escapeVerdict{name: span.name, subject: subject, expected: strings.HasPrefix(span.name, escapingPrefix)}
+		for line := span.start; line <= span.end; line++ {
+			for _, msg := range escapes[line] {
+				if diagnosticSubject(msg) == subject && !slices.Contains(v.diagnostics, msg) {
+					v.diagnostics = append(v.diagnostics, msg)
+				}
+			}
+		}
+		v.reported = len(v.diagnostics) > 0
+		verdicts = append(verdicts, v)
+	}
+	return verdicts, nil
+}
+
+// sourceDir is the directory this file was compiled from, if it still
+// holds the module: go run and go build of a checkout leave it in place,
+// while -trimpath or a binary copied elsewhere don't
+func sourceDir() (string, bool) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok || !filepath.IsAbs(file) {
+		return "", false
+	}
+	dir := filepath.Dir(file)
+	for _, name := range []string{escapeCatalogFile, "go.mod"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return "", false
+		}
+	}
+	return dir, true
+}
+
+// DemonstrateEscapeVerifier rebuilds the package with escape diagnostics and
+// checks every escapesVia*/noEscape* example against its name
+func DemonstrateEscapeVerifier() error {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ESCAPE ANALYSIS, ACCORDING TO THE COMPILER")
+	fmt.Fprintln(out, "============================================================")
+
+	dir, ok := sourceDir()
+	if !ok {
+		fmt.Fprintf(out, "  Skipped: the source (%s) is not next to this binary's build path\n", escapeCatalogFile)
+		return nil
+	}
+	if _, err := exec.LookPath("go"); errors.Is(err, exec.ErrNotFound) {
+		fmt.Fprintln(out, "  Skipped: no go command in PATH")
+		return nil
+	}
+
+	spans, err := catalogFunctions(filepath.Join(dir, escapeCatalogFile))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", escapeCatalogFile, err)
+	}
+	escapes, err := compilerEscapes(dir, escapeCatalogFile)
+	if err != nil {
+		return err
+	}
+
+	verdicts, err := verifyEscapes(spans, escapes)
+	if err != nil {
+		return err
+	}
+
+	mismatches := 0
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Function\tSubject\tExpected\tCompiler\tMatch\tDiagnostic")
+	for _, v := range verdicts {
+		status := "ok"
+		if v.expected != v.reported {
+			status = "MISMATCH"
+			mismatches++
+		}
+		first := ""
+		if len(v.diagnostics) > 0 {
+			first = v.diagnostics[0]
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", v.name, v.subject, escapeLabel(v.expected), escapeLabel(v.reported), status, first)
+		observe(v.name, boolValue(v.reported), "compiler reported escape")
+	}
+	tw.Flush()
+
+	fmt.Fprintf(out, "\n  %d mismatches. A function's name documents its behavior (escapesVia*\n", mismatches)
+	fmt.Fprintln(out, "  must escape, noEscape* must not) for its subject; other diagnostics in")
+	fmt.Fprintln(out, "  the function are ignored. A mismatch means this compiler version decides")
+	fmt.Fprintln(out, "  differently than the catalog claims.")
+	return nil
+}
+
+// escapeLabel renders an escape verdict for the table
+func escapeLabel(escapes bool) string {
+	if escapes {
+		return "escapes"
+	}
+	return "stays"
+}
+
+// boolValue encodes a bool as an observation value
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
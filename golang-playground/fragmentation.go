@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// This file fragments the heap on purpose: it frees every other object of
+// one size, so no span empties, then watches the four heap counters as the
+// holes get refilled, the rest is freed and the scavenger hands pages back
+
+func init() {
+	Register(demo{
+		name:        "fragmentation",
+		description: "Free every other mid-size object: HeapAlloc vs HeapInuse vs HeapSys vs HeapReleased",
+		category:    categoryGC,
+		run:         DemonstrateFragmentation,
+	})
+}
+
+// Hold the objects DemonstrateFragmentation punches holes into, and the
+// larger ones it allocates next to them
+var fragmentedObjects, refillObjects [][]byte
+
+const (
+	fragmentObjects = 50_000
+	fragmentSize    = 2 << 10 // 2KB: its own size class, four objects per 8KB span
+	refillSize      = 4 << 10 // a different size class, which can't use the 2KB holes
+
+	// How long to watch the scavenger, sampling every -scavenge-interval
+	fragmentScavengeWindow = 3 * time.Second
+)
+
+// fragmentationSampler prints one row of heap counters per call, stamped
+// with the time since it was created
+type fragmentationSampler struct {
+	start time.Time
+}
+
+func (s fragmentationSampler) sample(phase string) memSnapshot {
+	var m memSnapshot
+	readSnapshot(&m)
+	fmt.Fprintf(out, "  %6.2fs  %-32s %9.1f %9.1f %9.1f %9.1f\n",
+		time.Since(s.start).Seconds(), phase, toMB(m.HeapAlloc), toMB(m.HeapInuse), toMB(m.HeapSys), toMB(m.HeapReleased))
+	return m
+}
+
+// allocateObjects returns n touched objects of size bytes each
+func allocateObjects(n, size int) [][]byte {
+	objects := make([][]byte, n)
+	for i := range objects {
+		objects[i] = make([]byte, size)
+		objects[i][0] = 1
+	}
+	return objects
+}
+
+// Demonstrate fragmentation and the scavenger returning memory to the OS
+func DemonstrateFragmentation() error {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "HEAP FRAGMENTATION AND THE SCAVENGER")
+	fmt.Fprintln(out, "============================================================")
+
+	interval := *scavengeIntervalFlag
+	if interval <= 0 {
+		return fmt.Errorf("-scavenge-interval must be positive, got %v", interval)
+	}
+	fmt.Fprintf(out, "  %d objects of %s, every other one freed (MB)\n\n", fragmentObjects, formatBytes(fragmentSize))
+	fmt.Fprintf(out, "  %7s  %-32s %9s %9s %9s %9s\n", "time", "phase", "HeapAlloc", "HeapInuse", "HeapSys", "Released")
+
+	runtime.GC()
+	s := fragmentationSampler{start: time.Now()}
+	s.sample("start")
+
+	fragmentedObjects = allocateObjects(fragmentObjects, fragmentSize)
+	s.sample("allocated")
+
+	for i := 1; i < len(fragmentedObjects); i += 2 {
+		fragmentedObjects[i] = nil
+	}
+	runtime.GC()
+	holes := s.sample("every other one freed")
+	observe("fragmented heap in use but free", float64(holes.HeapInuse-holes.HeapAlloc), "bytes")
+
+	refillObjects = allocateObjects(fragmentObjects/2, refillSize)
+	runtime.GC()
+	grown := s.sample(fmt.Sprintf("+%d x %s", len(refillObjects), formatBytes(refillSize)))
+	observe("heap growth despite free holes", float64(grown.HeapSys-holes.HeapSys), "bytes")
+
+	for i := 1; i < len(fragmentedObjects); i += 2 {
+		fragmentedObjects[i] = make([]byte, fragmentSize)
+	}
+	runtime.GC()
+	s.sample(fmt.Sprintf("+%d x %s (into the holes)", fragmentObjects/2, formatBytes(fragmentSize)))
+
+	fragmentedObjects, refillObjects = nil, nil
+	runtime.GC()
+	s.sample("everything freed")
+	// The scavenger skips pages used during the current GC cycle, so the
+	// freed spans only become eligible once another cycle has passed
+	runtime.GC()
+	s.sample("one more GC")
+	for scavenging := time.Now(); time.Since(scavenging) < fragmentScavengeWindow; {
+		time.Sleep(interval)
+		s.sample("scavenger running")
+	}
+
+	fmt.Fprintln(out, "\n  The heap carves each span into objects of one size class. Freeing every")
+	fmt.Fprintln(out, "  other 2KB object halves HeapAlloc but leaves every span partly live, so")
+	fmt.Fprintln(out, "  HeapInuse doesn't move and nothing can go back to the OS. The holes only")
+	fmt.Fprintln(out, "  fit 2KB objects: 4KB ones need fresh spans and HeapSys grows, while 2KB")
+	fmt.Fprintln(out, "  ones slot in for free. Once whole spans are empty, HeapInuse drops, but")
+	fmt.Fprintln(out, "  HeapSys doesn't: the background scavenger leaves pages alone for a GC cycle")
+	fmt.Fprintln(out, "  after their last use, then returns them in batches (HeapReleased); the")
+	fmt.Fprintln(out, "  mapping stays. Go's allocator is built like jemalloc's size classes and")
+	fmt.Fprintln(out, "  suffers the same way; neither moves live objects, so fragmentation lasts")
+	fmt.Fprintln(out, "  as long as one object in each span does. Rust's allocator returns memory")
+	fmt.Fprintln(out, "  at free(), by its own policy - jemalloc decays dirty pages over time too.")
+	return nil
+}
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"strings"
+	"time"
+)
+
+// This file observes the garbage collector itself - when it runs and what it frees
+
+func init() {
+	Register(newDemo("ballast", "GC cycles with and without a large ballast slice", DemonstrateBallast).in(categoryGC))
+	Register(newDemo("finalizer-hazards", "Finalizer resurrection and finalizers stuck in cycles", DemonstrateFinalizerHazards).in(categoryGC))
+	Register(newDemo("gc-collection", "HeapObjects before and after dropping references", DemonstrateGCCollection).in(categoryGC))
+	Register(newDemo("gogc-tuning", "The same workload under GOGC=25, 100, 400 and off", DemonstrateGOGCTuning).in(categoryGC))
+	Register(newDemo("memory-limit", "GC frequency as the heap approaches a debug.SetMemoryLimit soft limit", DemonstrateMemoryLimit).in(categoryGC))
+	Register(newDemo("gc-scan-cost", "GC time over 64MB of pointer-free payloads vs 64MB of linked nodes", DemonstrateGCScanCost).in(categoryGC))
+	Register(newDemo("gc-observer", "Timeline of GC cycles and pause histogram from runtime/metrics", DemonstrateGC).in(categoryGC))
+}
+
+// Holds the objects DemonstrateGCCollection allocates until it drops them
+var collectableObjects []*LargeObject
+
+const collectableCount = 1000
+
+// Sink for short-lived garbage so the compiler can't drop the allocations
+var garbageSink []byte
+
+const (
+	garbageAllocations = 250_000
+	garbageSize        = 4 * 1024          // 4KB per allocation, ~1GB total
+	ballastSize        = 256 * 1024 * 1024 // 256MB, never touched
+)
+
+// The GOMEMLIMIT matching a ballast: with GOGC=100 the ballast lets the heap
+// reach twice its size before a collection
+const ballastEquivalentLimit = 2 * ballastSize
+
+// Churn through short-lived allocations, the way a busy server would
+func allocateGarbage() {
+	for i := 0; i < garbageAllocations; i++ {
+		garbageSink = make([]byte, garbageSize)
+	}
+	garbageSink = nil
+}
+
+// Count the GC cycles that happen while fn runs
+func countGCCycles(fn func()) uint32 {
+	var before, after memSnapshot
+	runtime.GC()
+	readSnapshot(&before)
+	fn()
+	readSnapshot(&after)
+	return after.NumGC - before.NumGC
+}
+
+// ballastRun is one row of the ballast comparison: the GC cycles the
+// workload took and the resident memory it left behind
+type ballastRun struct {
+	setup  string
+	cycles uint32
+	rss    string
+}
+
+// runBallastWorkload counts the workload's GC cycles, starting from a heap
+// with its free pages returned so each run's RSS is its own
+func runBallastWorkload(setup string) ballastRun {
+	debug.FreeOSMemory()
+	run := ballastRun{setup: setup, cycles: countGCCycles(allocateGarbage), rss: "unavailable"}
+	if mem, err := ProcessMemory(); err == nil {
+		run.rss = formatBytes(mem.RSS)
+	}
+	observe(setup+" GC cycles", float64(run.cycles), "cycles")
+	return run
+}
+
+// Demonstrate the pre-GOMEMLIMIT ballast trick for reducing GC frequency,
+// next to the GOMEMLIMIT setting that replaced it
+func DemonstrateBallast() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "MEMORY BALLAST VS GOMEMLIMIT")
+	fmt.Fprintln(out, "============================================================")
+
+	var runs []ballastRun
+	runs = append(runs, runBallastWorkload("GOGC=100"))
+
+	// The ballast is pointer-free and never written, so the OS need not
+	// back its pages with real memory - but the GC counts it as live heap.
+	// Whether it stays unbacked depends on whether the runtime must zero it.
+	before, err := ProcessMemory()
+	ballast := make([]byte, ballastSize)
+	after, _ := ProcessMemory()
+	runs = append(runs, runBallastWorkload(fmt.Sprintf("GOGC=100 + %s ballast", formatBytes(ballastSize))))
+	runtime.KeepAlive(ballast)
+
+	// The modern equivalent: no proportional trigger at all, collect only as
+	// the whole runtime's memory approaches the limit
+	oldPercent := debug.SetGCPercent(-1)
+	oldLimit := debug.SetMemoryLimit(ballastEquivalentLimit)
+	runs = append(runs, runBallastWorkload(fmt.Sprintf("GOGC=off + GOMEMLIMIT=%s", formatBytes(ballastEquivalentLimit))))
+	debug.SetMemoryLimit(oldLimit)
+	debug.SetGCPercent(oldPercent)
+
+	fmt.Fprintf(out, "  Workload: %d allocations of %s (%s in total)\n\n", garbageAllocations, formatBytes(garbageSize), formatBytes(garbageAllocations*garbageSize))
+	fmt.Fprintf(out, "  %-32s %9s  %s\n", "Setup", "GC cycles", "RSS after")
+	for _, r := range runs {
+		fmt.Fprintf(out, "  %-32s %9d  %s\n", r.setup, r.cycles, r.rss)
+	}
+	if err == nil {
+		fmt.Fprintf(out, "\n  Allocating the ballast itself moved RSS by %s\n", formatBytes(after.RSS-min(before.RSS, after.RSS)))
+	}
+
+	fmt.Fprintln(out, "\n  The GC triggers when the heap grows by GOGC% (default 100%) over the live")
+	fmt.Fprintln(out, "  heap left by the last cycle. A large live ballast raises that trigger point,")
+	fmt.Fprintln(out, "  so the same garbage fits between fewer collections. Untouched, it should")
+	fmt.Fprintln(out, "  cost address space, not RSS - but only fresh pages from the OS are known")
+	fmt.Fprintln(out, "  to be zero. If its span starts in heap memory used before, the runtime")
+	fmt.Fprintln(out, "  zeroes all of it and the ballast is resident after all. GOMEMLIMIT")
+	fmt.Fprintln(out, "  (Go 1.19+) states the goal directly: with GOGC=off the GC runs only as")
+	fmt.Fprintln(out, "  total memory nears the limit - the same effect with no dummy allocation,")
+	fmt.Fprintln(out, "  and a limit that still holds if the live heap grows, where the ballast")
+	fmt.Fprintln(out, "  would let the goal double with it. Keep GOGC on with a limit when the live")
+	fmt.Fprintln(out, "  heap is unpredictable: GOGC=off with a live heap near the limit collects")
+	fmt.Fprintln(out, "  constantly.")
+}
+
+func heapObjects() uint64 {
+	var m memSnapshot
+	readSnapshot(&m)
+	return m.HeapObjects
+}
+
+// Demonstrate that the GC really frees objects once the last reference is gone
+func DemonstrateGCCollection() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "PROVING THE GC COLLECTED IT")
+	fmt.Fprintln(out, "============================================================")
+
+	runtime.GC()
+	baseline := heapObjects()
+
+	collectableObjects = make([]*LargeObject, collectableCount)
+	for i := range collectableObjects {
+		collectableObjects[i] = createLargeObject(i)
+	}
+	runtime.GC() // the objects are still referenced - nothing to collect
+	alive := heapObjects()
+
+	collectableObjects = nil // drop the only reference
+	runtime.GC()
+	collected := heapObjects()
+
+	fmt.Fprintf(out, "  %-38s %d\n", "Heap objects at baseline:", baseline)
+	fmt.Fprintf(out, "  %-38s %d (+%d)\n", fmt.Sprintf("After allocating %d objects (+GC):", collectableCount), alive, alive-baseline)
+	fmt.Fprintf(out, "  %-38s %d\n", "After dropping them (+GC):", collected)
+	if alive > collected {
+		fmt.Fprintf(out, "  The GC freed %d objects\n", alive-collected)
+	}
+	fmt.Fprintln(out, "\n  The runtime always has objects of its own, so only the deltas matter:")
+	fmt.Fprintln(out, "  each LargeObject is 2 heap objects (the struct and its Data array),")
+	fmt.Fprintln(out, "  plus 1 for the slice holding the pointers")
+}
+
+// finalizable is a small heap object for the finalizer experiments
+type finalizable struct {
+	next *finalizable
+	id   int
+}
+
+// Where the resurrecting finalizer stores its object
+var resurrected *finalizable
+
+// How many GC cycles awaitFinalizer forces before giving up
+const finalizerGCAttempts = 10
+
+// awaitFinalizer forces GC cycles until a finalizer reports on ran, or gives
+// up - finalizers run on their own goroutine some time after a cycle
+func awaitFinalizer(ran <-chan string) (string, bool) {
+	for i := 0; i < finalizerGCAttempts; i++ {
+		runtime.GC()
+		select {
+		case msg := <-ran:
+			return msg, true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return "", false
+}
+
+// Demonstrate why runtime.SetFinalizer is no substitute for Rust's Drop
+func DemonstrateFinalizerHazards() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "FINALIZER HAZARDS")
+	fmt.Fprintln(out, "============================================================")
+
+	ran := make(chan string, 1)
+
+	// Hazard 1: a finalizer can resurrect its object
+	func() {
+		obj := &finalizable{id: 1}
+		runtime.SetFinalizer(obj, func(f *finalizable) {
+			resurrected = f // object is reachable again
+			ran <- "resurrecting finalizer ran"
+		})
+	}()
+	msg, ok := awaitFinalizer(ran)
+	fmt.Fprintln(out, "  1. Resurrection")
+	fmt.Fprintf(out, "     first GC:  finalizer ran=%t (%s), object alive again=%t\n", ok, msg, resurrected != nil)
+	resurrected = nil
+	_, ok = awaitFinalizer(ran)
+	fmt.Fprintf(out, "     dropped again: finalizer ran=%t - it was cleared after the first run,\n", ok)
+	fmt.Fprintln(out, "     so the resurrected object is now freed without any cleanup at all")
+
+	// Hazard 2: a finalizer on an object in a cycle never runs
+	func() {
+		a, b := &finalizable{id: 2}, &finalizable{id: 3}
+		a.next, b.next = b, a
+		runtime.SetFinalizer(a, func(*finalizable) { ran <- "cycle finalizer ran" })
+	}()
+	_, ok = awaitFinalizer(ran)
+	fmt.Fprintln(out, "\n  2. Reference cycle (a <-> b, finalizer on a)")
+	fmt.Fprintf(out, "     after %d GC cycles: finalizer ran=%t - the cycle is leaked\n", finalizerGCAttempts, ok)
+	fmt.Fprintln(out, "     The GC marks everything a finalizer-bearing object points to (the")
+	fmt.Fprintln(out, "     finalizer may need it), so b keeps a reachable and a is never finalized.")
+
+	// The modern alternative: runtime.AddCleanup doesn't get the object back
+	func() {
+		a, b := &finalizable{id: 4}, &finalizable{id: 5}
+		a.next, b.next = b, a
+		runtime.AddCleanup(a, func(msg string) { ran <- msg }, "cycle cleanup ran")
+	}()
+	msg, ok = awaitFinalizer(ran)
+	fmt.Fprintf(out, "     same cycle with runtime.AddCleanup: ran=%t (%s)\n", ok, msg)
+
+	fmt.Fprintln(out, "\n  Finalizers run late, on another goroutine, maybe never. Rust's Drop runs")
+	fmt.Fprintln(out, "  exactly once, right when the owner goes out of scope. In Go, release")
+	fmt.Fprintln(out, "  resources explicitly (defer Close()) and treat finalizers as a safety net.")
+}
+
+// runtime/metrics read by DemonstrateGC
+const (
+	metricGCCycles = "/gc/cycles/total:gc-cycles"
+	metricHeapGoal = "/gc/heap/goal:bytes"
+	metricHeapLive = "/gc/heap/live:bytes"
+	metricGCPauses = "/sched/pauses/total/gc:seconds"
+)
+
+const (
+	gcObserveAllocations = 50000 // of garbageSize each, ~200MB in total
+	gcObserveSampleEvery = 250   // allocations between metric reads
+	gcTimelineMaxRows    = 15
+)
+
+// gcEvent is the state right after the observer noticed a new cycle
+type gcEvent struct {
+	at       time.Duration
+	cycle    uint64
+	heapGoal uint64
+	liveHeap uint64
+}
+
+// histogramQuantile returns the upper bound of the bucket holding quantile q
+// of counts (a delta of h.Counts), capped at the last finite boundary
+func histogramQuantile(h *metrics.Float64Histogram, counts []uint64, q float64) float64 {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= target {
+			if upper := h.Buckets[i+1]; !math.IsInf(upper, 1) {
+				return upper
+			}
+			return h.Buckets[i]
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1]
+}
+
+// Demonstrate what the collector does while a program allocates
+func DemonstrateGC() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "GC CYCLES AND PAUSES (runtime/metrics)")
+	fmt.Fprintln(out, "============================================================")
+
+	samples := []metrics.Sample{{Name: metricGCCycles}, {Name: metricHeapGoal}, {Name: metricHeapLive}, {Name: metricGCPauses}}
+	runtime.GC()
+	metrics.Read(samples)
+	startCycle := samples[0].Value.Uint64()
+	startPauses := append([]uint64(nil), samples[3].Value.Float64Histogram().Counts...)
+
+	var events []gcEvent
+	lastCycle := startCycle
+	start := time.Now()
+	for i := 1; i <= gcObserveAllocations; i++ {
+		garbageSink = make([]byte, garbageSize)
+		if i%gcObserveSampleEvery != 0 {
+			continue
+		}
+		metrics.Read(samples)
+		if cycle := samples[0].Value.Uint64(); cycle > lastCycle {
+			events = append(events, gcEvent{
+				at:       time.Since(start),
+				cycle:    cycle - startCycle,
+				heapGoal: samples[1].Value.Uint64(),
+				liveHeap: samples[2].Value.Uint64(),
+			})
+			lastCycle = cycle
+		}
+	}
+	garbageSink = nil
+	elapsed := time.Since(start)
+	metrics.Read(samples)
+
+	fmt.Fprintf(out, "  Allocated %d x %d bytes in %v\n\n", gcObserveAllocations, garbageSize, elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "  %10s %6s %12s %12s\n", "Time", "Cycle", "Heap goal", "Live heap")
+	for i, e := range events {
+		if i == gcTimelineMaxRows {
+			fmt.Fprintf(out, "  ... %d more\n", len(events)-i)
+			break
+		}
+		fmt.Fprintf(out, "  %10v %6d %12s %12s\n", e.at.Round(time.Microsecond), e.cycle, formatBytes(e.heapGoal), formatBytes(e.liveHeap))
+	}
+
+	pauses := samples[3].Value.Float64Histogram()
+	counts := make([]uint64, len(pauses.Counts))
+	var pauseCount uint64
+	for i := range counts {
+		counts[i] = pauses.Counts[i] - startPauses[i]
+		pauseCount += counts[i]
+	}
+	cycles := samples[0].Value.Uint64() - startCycle
+	fmt.Fprintf(out, "\n  %d GC cycles, %d stop-the-world pauses\n", cycles, pauseCount)
+	for _, q := range []struct {
+		label string
+		q     float64
+	}{{"p50", 0.5}, {"p99", 0.99}, {"max", 1}} {
+		fmt.Fprintf(out, "  pause %s <= %v\n", q.label, time.Duration(histogramQuantile(pauses, counts, q.q)*float64(time.Second)))
+	}
+
+	fmt.Fprintln(out, "\n  The heap goal is where the next cycle starts: live heap after the last")
+	fmt.Fprintln(out, "  cycle plus GOGC%. Marking runs concurrently; the program only stops for")
+	fmt.Fprintln(out, "  two short pauses per cycle - but it never knows when. Rust has no cycles")
+	fmt.Fprintln(out, "  and no pauses: each drop frees its memory at a point fixed in the code.")
+}
+
+// Current bytes in heap objects (live or not yet swept) - HeapAlloc's
+// runtime/metrics equivalent, cheap enough to read inside a loop
+const metricHeapObjects = "/memory/classes/heap/objects:bytes"
+
+const (
+	gogcLiveSetSize = 32 << 20 // kept alive throughout, so GOGC has a base to scale
+	gogcChurnAllocs = 50000    // of garbageSize each, ~200MB of garbage
+)
+
+// GOGC values to compare; -1 switches the collector off
+var gogcSettings = []int{25, 100, 400, -1}
+
+// runGOGCWorkload holds a live set while churning garbage and returns the
+// highest heap size seen, sampled every gcObserveSampleEvery allocations
+func runGOGCWorkload() uint64 {
+	live := make([][]byte, gogcLiveSetSize/garbageSize)
+	for i := range live {
+		live[i] = make([]byte, garbageSize)
+	}
+
+	sample := []metrics.Sample{{Name: metricHeapObjects}}
+	var peak uint64
+	for i := 1; i <= gogcChurnAllocs; i++ {
+		garbageSink = make([]byte, garbageSize)
+		if i%gcObserveSampleEvery == 0 {
+			metrics.Read(sample)
+			peak = max(peak, sample[0].Value.Uint64())
+		}
+	}
+	garbageSink = nil
+	runtime.KeepAlive(live)
+	return peak
+}
+
+// gogcLabel renders a SetGCPercent value the way GOGC spells it
+func gogcLabel(percent int) string {
+	if percent < 0 {
+		return "off"
+	}
+	return fmt.Sprint(percent)
+}
+
+// Demonstrate the memory-for-CPU dial GOGC exposes
+func DemonstrateGOGCTuning() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "GOGC TUNING")
+	fmt.Fprintln(out, "============================================================")
+
+	original := debug.SetGCPercent(100)
+	defer debug.SetGCPercent(original)
+
+	fmt.Fprintf(out, "  Workload: %s live set + %d x %d-byte garbage allocations\n\n",
+		formatBytes(gogcLiveSetSize), gogcChurnAllocs, garbageSize)
+	fmt.Fprintf(out, "  %-6s %12s %10s %12s\n", "GOGC", "Peak heap", "GC cycles", "Wall time")
+	for _, percent := range gogcSettings {
+		debug.SetGCPercent(percent)
+		var peak uint64
+		start := time.Now()
+		cycles := countGCCycles(func() { peak = runGOGCWorkload() })
+		elapsed := time.Since(start)
+		runtime.GC() // drop this run's heap before the next setting
+
+		label := gogcLabel(percent)
+		fmt.Fprintf(out, "  %-6s %12s %10d %12v\n", label, formatBytes(peak), cycles, elapsed.Round(time.Millisecond))
+		observe("GOGC="+label+" peak heap", float64(peak), "bytes")
+		observe("GOGC="+label+" gc cycles", float64(cycles), "cycles")
+	}
+
+	fmt.Fprintln(out, "\n  GOGC=N lets the heap grow N% over the live set before the next cycle:")
+	fmt.Fprintln(out, "  lower values collect often and keep the heap small, higher values trade")
+	fmt.Fprintln(out, "  memory for fewer cycles. Off means no collection at all - the heap just")
+	fmt.Fprintln(out, "  grows. Rust has no such dial: memory is freed when its owner is dropped,")
+	fmt.Fprintln(out, "  so peak usage follows the program's own structure.")
+}
+
+const (
+	memLimit          = 64 << 20 // the soft limit under test
+	memLimitSteps     = 11
+	memLimitLiveStep  = 5 << 20 // live heap added per step
+	memLimitChurnStep = 4000    // garbageSize allocations per step
+	memLimitBarWidth  = 40      // characters standing for the whole limit
+)
+
+// Live allocations DemonstrateMemoryLimit grows step by step
+var memLimitLive [][]byte
+
+// Demonstrate the GC working harder as live heap nears GOMEMLIMIT
+func DemonstrateMemoryLimit() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "SOFT MEMORY LIMIT (debug.SetMemoryLimit)")
+	fmt.Fprintln(out, "============================================================")
+
+	originalLimit := debug.SetMemoryLimit(memLimit)
+	defer debug.SetMemoryLimit(originalLimit)
+	// A high GOGC on its own would let the heap grow far past the limit:
+	// here the limit, not GOGC, decides when collection happens
+	originalPercent := debug.SetGCPercent(400)
+	defer debug.SetGCPercent(originalPercent)
+
+	samples := []metrics.Sample{{Name: metricHeapObjects}, {Name: metricGCCycles}, {Name: metricHeapGoal}}
+	runtime.GC()
+	metrics.Read(samples)
+	lastCycles := samples[1].Value.Uint64()
+
+	fmt.Fprintf(out, "  Limit %s, GOGC=400. Each step adds %s live and churns %s of garbage.\n\n",
+		formatBytes(memLimit), formatBytes(memLimitLiveStep), formatBytes(memLimitChurnStep*garbageSize))
+	fmt.Fprintf(out, "  %9s %10s %10s %7s  %s\n", "Live", "Peak heap", "Heap goal", "GCs", "peak vs limit")
+	for step := 1; step <= memLimitSteps; step++ {
+		for i := 0; i < memLimitLiveStep/garbageSize; i++ {
+			memLimitLive = append(memLimitLive, make([]byte, garbageSize))
+		}
+
+		var peak uint64
+		for i := 1; i <= memLimitChurnStep; i++ {
+			garbageSink = make([]byte, garbageSize)
+			if i%gcObserveSampleEvery == 0 {
+				metrics.Read(samples)
+				peak = max(peak, samples[0].Value.Uint64())
+			}
+		}
+		metrics.Read(samples)
+		cycles := samples[1].Value.Uint64()
+
+		bar := min(memLimitBarWidth, int(peak*memLimitBarWidth/memLimit))
+		fmt.Fprintf(out, "  %9s %10s %10s %7d  |%s%s|\n", formatBytes(uint64(step*memLimitLiveStep)), formatBytes(peak),
+			formatBytes(samples[2].Value.Uint64()), cycles-lastCycles,
+			strings.Repeat("#", bar), strings.Repeat(" ", memLimitBarWidth-bar))
+		lastCycles = cycles
+	}
+	memLimitLive, garbageSink = nil, nil
+
+	fmt.Fprintln(out, "\n  Far from the limit, GOGC=400 sets a distant heap goal and cycles are")
+	fmt.Fprintln(out, "  rare. Near it, the goal is clamped to the limit, so the same garbage")
+	fmt.Fprintln(out, "  triggers more and more cycles. The limit is soft: if live data alone")
+	fmt.Fprintln(out, "  exceeds it, Go keeps running (capping GC CPU at ~50%) instead of failing.")
+	fmt.Fprintln(out, "  Rust has no GC to tune; an allocation over a limit simply fails.")
+}
+
+// The GC's own CPU time, estimated by the runtime and updated as each cycle
+// ends: marking, assists and the idle-priority workers together
+const metricGCCPU = "/cpu/classes/gc/total:cpu-seconds"
+
+// Both scan-cost heaps hold this many bytes, in objects of 16 bytes
+const (
+	scanHeapBytes = 64 << 20
+	scanHeapItems = scanHeapBytes / 16
+	scanCycles    = 5
+)
+
+// scanPayload is 16 bytes the GC never has to look inside
+type scanPayload struct {
+	data [16]byte
+}
+
+// scanNode is 16 bytes of nothing but pointers, linked into a list
+type scanNode struct {
+	next, prev *scanNode
+}
+
+// The heap under test, kept reachable from a global like a real cache
+var (
+	scanPayloads []scanPayload
+	scanList     *scanNode
+)
+
+// buildNoscanHeap fills the heap with one pointer-free slice of payloads
+func buildNoscanHeap() {
+	scanPayloads = make([]scanPayload, scanHeapItems)
+}
+
+// buildPointerHeap fills the heap with the same bytes as individually
+// allocated nodes of a doubly linked list, every word a pointer to follow
+func buildPointerHeap() {
+	for i := 0; i < scanHeapItems; i++ {
+		n := &scanNode{next: scanList}
+		if scanList != nil {
+			scanList.prev = n
+		}
+		scanList = n
+	}
+}
+
+// dropScanHeaps releases whichever heap was built
+func dropScanHeaps() {
+	scanPayloads, scanList = nil, nil
+	runtime.GC()
+}
+
+// gcCPUSeconds reads the GC CPU time the runtime has accounted so far
+func gcCPUSeconds() float64 {
+	sample := []metrics.Sample{{Name: metricGCCPU}}
+	metrics.Read(sample)
+	return sample[0].Value.Float64()
+}
+
+// timeGCCycles runs n forced collections over the live heap and returns the
+// wall time and GC CPU time per cycle
+func timeGCCycles(n int) (wall, cpu time.Duration) {
+	runtime.GC() // finish sweeping whatever building the heap left behind
+	startCPU, start := gcCPUSeconds(), time.Now()
+	for i := 0; i < n; i++ {
+		runtime.GC()
+	}
+	wall = time.Since(start) / time.Duration(n)
+	cpu = time.Duration((gcCPUSeconds() - startCPU) / float64(n) * float64(time.Second))
+	return wall, cpu
+}
+
+// Demonstrate that the GC's cost follows pointers, not bytes
+func DemonstrateGCScanCost() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "GC SCAN COST: POINTER-FREE VS POINTER-RICH HEAP")
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintf(out, "  %s live either way, %d forced GC cycles each\n\n", formatBytes(scanHeapBytes), scanCycles)
+
+	heaps := []struct {
+		label   string
+		objects int
+		build   func()
+	}{
+		{"[]scanPayload (noscan)", 1, buildNoscanHeap},
+		{"linked *scanNode list", scanHeapItems, buildPointerHeap},
+	}
+	fmt.Fprintf(out, "  %-24s %10s %14s %14s\n", "Heap", "Objects", "Wall per GC", "GC CPU per GC")
+	for _, h := range heaps {
+		h.build()
+		wall, cpu := timeGCCycles(scanCycles)
+		dropScanHeaps()
+		fmt.Fprintf(out, "  %-24s %10d %14v %14v\n", h.label, h.objects, wall.Round(time.Microsecond), cpu.Round(time.Microsecond))
+		observe(h.label+" GC CPU per cycle", float64(cpu.Nanoseconds()), "ns")
+	}
+
+	fmt.Fprintln(out, "\n  Marking means following every pointer in every live object. A span of")
+	fmt.Fprintln(out, "  pointer-free objects is allocated \"noscan\": the GC marks the object and")
+	fmt.Fprintln(out, "  never reads its contents, so 64MB of bytes costs about as much as one")
+	fmt.Fprintln(out, "  small object. The same 64MB as linked nodes is millions of objects and")
+	fmt.Fprintln(out, "  pointers to chase, and the GC pays for them on every cycle for as long as")
+	fmt.Fprintln(out, "  they live. Keeping large long-lived data pointer-free - indices instead")
+	fmt.Fprintln(out, "  of pointers, []byte instead of []*T, strings packed into one buffer -")
+	fmt.Fprintln(out, "  is the GC tuning that needs no knobs. Rust has no tracing GC: its linked")
+	fmt.Fprintln(out, "  list costs an allocation per node, but nothing per collection.")
+}
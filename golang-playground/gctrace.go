@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// This file implements the gctrace subcommand: it re-runs the demos in a
+// child process with GODEBUG=gctrace=1 and turns the one-line-per-cycle
+// trace the runtime writes to stderr into a table, so nobody has to decode
+//
+//	gc 7 @0.031s 4%: 0.011+0.45+0.002 ms clock, ..., 5->5->1 MB, 6 MB goal, ...
+
+// gcTraceCycle is one gctrace line: when the cycle ran, its two
+// stop-the-world pauses, and the heap in MB at its start, at the end of
+// marking and left live, against the goal it was paced for
+type gcTraceCycle struct {
+	cycle       int
+	at          float64 // seconds since the program started
+	cpuPercent  int     // share of CPU spent in GC since start
+	sweepTermMs float64 // stop-the-world
+	markMs      float64 // concurrent
+	markTermMs  float64 // stop-the-world
+	heapStart   int
+	heapEnd     int
+	heapLive    int
+	goal        int
+	forced      bool
+}
+
+// gcTraceLine matches the fields gcTraceCycle keeps from the runtime's format
+var gcTraceLine = regexp.MustCompile(`^gc (\d+) @([\d.]+)s (\d+)%: ([\d.]+)\+([\d.]+)\+([\d.]+) ms clock, .*?(\d+)->(\d+)->(\d+) MB, (\d+) MB goal.*?( \(forced\))?$`)
+
+// With GOGC=off the runtime reports a goal of "infinity", roughly 2^63
+// bytes; anything past this many MB is printed as off
+const gcTraceGoalOff = 1 << 40
+
+// How many cycles the gctrace table lists before only summarizing the rest
+const gcTraceRows = 40
+
+// parseGCTrace reads gctrace lines from r, copying every other line to
+// passthrough so the child's own errors still show
+func parseGCTrace(r io.Reader, passthrough io.Writer) ([]gcTraceCycle, error) {
+	var cycles []gcTraceCycle
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := gcTraceLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			fmt.Fprintln(passthrough, scanner.Text())
+			continue
+		}
+		num := func(i int) float64 {
+			v, _ := strconv.ParseFloat(m[i], 64) // the regexp only matched digits
+			return v
+		}
+		cycles = append(cycles, gcTraceCycle{
+			cycle:       int(num(1)),
+			at:          num(2),
+			cpuPercent:  int(num(3)),
+			sweepTermMs: num(4),
+			markMs:      num(5),
+			markTermMs:  num(6),
+			heapStart:   int(num(7)),
+			heapEnd:     int(num(8)),
+			heapLive:    int(num(9)),
+			goal:        int(num(10)),
+			forced:      m[11] != "",
+		})
+	}
+	return cycles, scanner.Err()
+}
+
+// GCTrace runs the selected demos (all of them if demoName is empty) in a
+// child process under GODEBUG=gctrace=1 and summarizes every GC cycle
+func GCTrace(demoName string) error {
+	if _, err := selectDemos(demoName); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("gctrace: %w", err)
+	}
+	args := append(forwardedFlags(), "-quiet")
+	if demoName != "" {
+		args = append(args, "-demo="+demoName)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), "GODEBUG="+strings.TrimPrefix(os.Getenv("GODEBUG")+",gctrace=1", ","))
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("gctrace: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("gctrace: %w", err)
+	}
+	cycles, perr := parseGCTrace(stderr, os.Stderr)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("gctrace: child process: %w", err)
+	}
+	if perr != nil {
+		return fmt.Errorf("gctrace: reading the trace: %w", perr)
+	}
+	printGCTrace(os.Stdout, cycles)
+	return nil
+}
+
+// printGCTrace tabulates the first gcTraceRows cycles and summarizes them all
+func printGCTrace(w io.Writer, cycles []gcTraceCycle) {
+	if len(cycles) == 0 {
+		fmt.Fprintln(w, "No GC cycles ran.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Cycle\tAt\tSTW pause\tConcurrent mark\tHeap before\tAfter mark\tLive\tGoal\tGC CPU\t")
+	for _, c := range cycles[:min(len(cycles), gcTraceRows)] {
+		forced, goal := "", fmt.Sprintf("%d MB", c.goal)
+		if c.forced {
+			forced = " (forced)"
+		}
+		if c.goal >= gcTraceGoalOff {
+			goal = "off"
+		}
+		fmt.Fprintf(tw, "%d\t%.3fs\t%.3f ms\t%.3f ms\t%d MB\t%d MB\t%d MB\t%s\t%d%%\t%s\n",
+			c.cycle, c.at, c.sweepTermMs+c.markTermMs, c.markMs, c.heapStart, c.heapEnd, c.heapLive, goal, c.cpuPercent, forced)
+	}
+	tw.Flush()
+	if len(cycles) > gcTraceRows {
+		fmt.Fprintf(w, "... and %d more cycles\n", len(cycles)-gcTraceRows)
+	}
+
+	var forced int
+	var totalPause, maxPause float64
+	var peakHeap, peakGoal int
+	for _, c := range cycles {
+		pause := c.sweepTermMs + c.markTermMs
+		totalPause += pause
+		maxPause = max(maxPause, pause)
+		peakHeap = max(peakHeap, c.heapEnd)
+		if c.goal < gcTraceGoalOff {
+			peakGoal = max(peakGoal, c.goal)
+		}
+		if c.forced {
+			forced++
+		}
+	}
+	last := cycles[len(cycles)-1]
+	fmt.Fprintf(w, "\n%d cycles (%d forced by runtime.GC or debug calls) over %.2fs\n", len(cycles), forced, last.at)
+	fmt.Fprintf(w, "Stop-the-world pauses: %.3f ms in total, %.3f ms at most\n", totalPause, maxPause)
+	fmt.Fprintf(w, "Largest heap: %d MB, largest goal: %d MB; GC used %d%% of CPU time\n", peakHeap, peakGoal, last.cpuPercent)
+	fmt.Fprintln(w, "\nSTW pause is the sweep-termination plus mark-termination phases; marking")
+	fmt.Fprintln(w, "itself runs concurrently with the program. Heap before and after mark show")
+	fmt.Fprintln(w, "the allocation that happened while marking; Live is what survived, and the")
+	fmt.Fprintln(w, "next goal is Live grown by GOGC%, unless GOMEMLIMIT caps it.")
+}
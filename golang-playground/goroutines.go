@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// This file explores goroutine stacks - growable, copyable, and GC-managed
+
+func init() {
+	Register(newDemo("stack-growth", "Goroutine stacks grow by copying and shrink after GC", DemonstrateStackGrowth).in(categoryGoroutines))
+	Register(demo{
+		name:        "goroutine-footprint",
+		description: "Memory per idle goroutine at 1k, 10k and 100k goroutines (see -goroutines)",
+		category:    categoryGoroutines,
+		run:         DemonstrateGoroutineFootprint,
+	})
+}
+
+const stackGrowthDepth = 10000
+
+// stackMove is a depth at which the goroutine's first frame was found at a
+// new address - the whole stack had been copied somewhere bigger
+type stackMove struct {
+	depth int
+	addr  uintptr
+}
+
+// Recurse with a local buffer in every frame so the stack has to grow,
+// watching where anchor (a local in the goroutine's first frame) lives.
+// The runtime rewrites pointers into a stack when it copies it, so anchor
+// always holds the current address. At the deepest frame, signal the
+// caller and wait until it has sampled.
+func growStack(depth int, anchor *int, moves *[]stackMove, reached chan<- struct{}, release <-chan struct{}) byte {
+	var frame [128]byte
+	frame[depth%len(frame)] = byte(depth)
+	if addr := uintptr(unsafe.Pointer(anchor)); addr != (*moves)[len(*moves)-1].addr {
+		*moves = append(*moves, stackMove{depth: stackGrowthDepth - depth, addr: addr})
+	}
+	if depth == 0 {
+		reached <- struct{}{}
+		<-release
+		return frame[0]
+	}
+	return growStack(depth-1, anchor, moves, reached, release) + frame[depth%len(frame)]
+}
+
+func stackInuse() uint64 {
+	var m memSnapshot
+	readSnapshot(&m)
+	return m.StackInuse
+}
+
+// runtime/metrics describing goroutine stacks
+const (
+	metricStackStart = "/gc/stack/starting-size:bytes"
+	metricHeapStacks = "/memory/classes/heap/stacks:bytes"
+	metricGoroutines = "/sched/goroutines:goroutines"
+)
+
+// stackMetrics reads the starting stack size, the memory all goroutine
+// stacks occupy and the number of goroutines
+func stackMetrics() (start, stacks, goroutines uint64) {
+	samples := []metrics.Sample{{Name: metricStackStart}, {Name: metricHeapStacks}, {Name: metricGoroutines}}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64(), samples[2].Value.Uint64()
+}
+
+// Demonstrate goroutine stacks growing by copying and shrinking again
+func DemonstrateStackGrowth() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "GOROUTINE STACK GROWTH")
+	fmt.Fprintln(out, "============================================================")
+
+	runtime.GC()
+	before := stackInuse()
+	startSize, stacksBefore, goroutinesBefore := stackMetrics()
+
+	reached := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	var moves []stackMove
+	go func() {
+		var anchor int
+		moves = []stackMove{{depth: 0, addr: uintptr(unsafe.Pointer(&anchor))}}
+		growStack(stackGrowthDepth, &anchor, &moves, reached, release)
+		close(done)
+	}()
+
+	<-reached
+	deep := stackInuse()
+	_, stacksDeep, goroutinesDeep := stackMetrics()
+	close(release)
+	<-done
+
+	runtime.GC()
+	after := stackInuse()
+
+	fmt.Fprintf(out, "  %-30s %8d bytes\n", "StackInuse before goroutine:", before)
+	fmt.Fprintf(out, "  %-30s %8d bytes (+%d)\n", fmt.Sprintf("StackInuse at depth %d:", stackGrowthDepth), deep, deep-before)
+	fmt.Fprintf(out, "  %-30s %8d bytes\n", "StackInuse after exit + GC:", after)
+
+	fmt.Fprintln(out, "\n  Address of a local in the goroutine's first frame, as the recursion deepens:")
+	for i, m := range moves {
+		note := "first frame"
+		if i > 0 {
+			note = fmt.Sprintf("stack copied, moved by %+d bytes", int64(m.addr)-int64(moves[i-1].addr))
+		}
+		fmt.Fprintf(out, "    depth %5d: %#x  %s\n", m.depth, m.addr, note)
+	}
+	observe("stack copies", float64(len(moves)-1), "copies")
+
+	fmt.Fprintln(out, "\n  runtime/metrics:")
+	fmt.Fprintf(out, "    %-40s %8d bytes\n", metricStackStart, startSize)
+	fmt.Fprintf(out, "    %-40s %8d bytes over %d goroutines (avg %d)\n", metricHeapStacks+" idle",
+		stacksBefore, goroutinesBefore, stacksBefore/max(goroutinesBefore, 1))
+	fmt.Fprintf(out, "    %-40s %8d bytes over %d goroutines (avg %d)\n", metricHeapStacks+" deep",
+		stacksDeep, goroutinesDeep, stacksDeep/max(goroutinesDeep, 1))
+	fmt.Fprintln(out, "\n  A goroutine starts with a tiny stack (a few KB). When a call would overflow")
+	fmt.Fprintln(out, "  it, the runtime allocates a stack twice the size, COPIES the old frames over")
+	fmt.Fprintln(out, "  and fixes up pointers into the stack - then keeps running. That is why the")
+	fmt.Fprintln(out, "  same local has a new address after each copy, and why Go never lets you")
+	fmt.Fprintln(out, "  keep a uintptr to a stack variable and expect it to stay valid.")
+	fmt.Fprintln(out, "  The GC shrinks oversized stacks and frees them when the goroutine exits.")
+	fmt.Fprintln(out, "  Rust threads get a fixed-size stack up front; overflow is fatal, not growth.")
+}
+
+// parseCounts parses a comma-separated list of positive counts
+func parseCounts(spec string) ([]int, error) {
+	var counts []int
+	for _, field := range strings.Split(spec, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad goroutine count %q in %q", field, spec)
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
+
+// idleGoroutine reports that it is running, then blocks until release is
+// closed and reports its exit. A plain function with arguments, not a
+// closure, so starting it allocates nothing on the heap beyond the goroutine
+// itself.
+func idleGoroutine(started, exited *sync.WaitGroup, release <-chan struct{}) {
+	defer exited.Done()
+	started.Done()
+	<-release
+}
+
+// goroutineFootprint is what n idle goroutines added, in bytes
+type goroutineFootprint struct {
+	n                 int
+	sys, stacks, heap uint64 // MemStats Sys, StackInuse, HeapAlloc deltas
+	metricStacks      uint64 // /memory/classes/heap/stacks:bytes delta
+}
+
+// measureGoroutines starts n idle goroutines, measures the memory they
+// hold once all are running, then releases them and waits for every one
+// to exit, so their teardown doesn't land in the next measurement
+func measureGoroutines(n int) goroutineFootprint {
+	var before, after memSnapshot
+	samples := []metrics.Sample{{Name: metricHeapStacks}}
+
+	runtime.GC()
+	readSnapshot(&before)
+	metrics.Read(samples)
+	stacksBefore := samples[0].Value.Uint64()
+
+	var started, exited sync.WaitGroup
+	release := make(chan struct{})
+	started.Add(n)
+	exited.Add(n)
+	for i := 0; i < n; i++ {
+		go idleGoroutine(&started, &exited, release)
+	}
+	started.Wait()
+
+	runtime.GC()
+	readSnapshot(&after)
+	metrics.Read(samples)
+	close(release)
+	exited.Wait()
+
+	delta := func(a, b uint64) uint64 { return b - min(a, b) }
+	return goroutineFootprint{
+		n:            n,
+		sys:          delta(before.Sys, after.Sys),
+		stacks:       delta(before.StackInuse, after.StackInuse),
+		heap:         delta(before.HeapAlloc, after.HeapAlloc),
+		metricStacks: delta(stacksBefore, samples[0].Value.Uint64()),
+	}
+}
+
+// DemonstrateGoroutineFootprint measures the memory per idle goroutine at
+// each -goroutines count
+func DemonstrateGoroutineFootprint() error {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "GOROUTINE MEMORY FOOTPRINT")
+	fmt.Fprintln(out, "============================================================")
+
+	counts, err := parseCounts(*goroutineCountsFlag)
+	if err != nil {
+		return err
+	}
+	start, _, _ := stackMetrics()
+	fmt.Fprintf(out, "  Starting stack size (%s): %d bytes\n\n", metricStackStart, start)
+	fmt.Fprintf(out, "  %10s %14s %10s %10s %10s %10s\n", "Goroutines", "Sys growth", "Sys/g", "Stack/g", "Heap/g", "Metric/g")
+	for _, n := range counts {
+		f := measureGoroutines(n)
+		perG := func(bytes uint64) uint64 { return bytes / uint64(f.n) }
+		fmt.Fprintf(out, "  %10d %14s %10d %10d %10d %10d\n",
+			f.n, formatBytes(f.sys), perG(f.sys), perG(f.stacks), perG(f.heap), perG(f.metricStacks))
+		observe(fmt.Sprintf("%d goroutines: stack bytes each", n), float64(perG(f.stacks)), "bytes")
+		observe(fmt.Sprintf("%d goroutines: sys bytes each", n), float64(perG(f.sys)), "bytes")
+	}
+	runtime.GC()
+
+	fmt.Fprintln(out, "\n  Stack/g is the goroutine's stack (StackInuse; Metric/g is the same from")
+	fmt.Fprintf(out, "  %s); Heap/g is mostly its g descriptor, a few\n", metricHeapStacks)
+	fmt.Fprintln(out, "  hundred bytes. Sys/g is what the process asked the OS for per goroutine.")
+	fmt.Fprintln(out, "  Sys doesn't shrink when they exit: the runtime keeps freed g's for reuse.")
+	fmt.Fprintln(out, "\n  A million goroutines fit in a few GB (-goroutines=1000000 shows it). A")
+	fmt.Fprintln(out, "  million OS threads don't: each reserves a fixed stack (8MB by default for")
+	fmt.Fprintln(out, "  Rust's std::thread on Linux) and costs a kernel task. Rust's async tasks")
+	fmt.Fprintln(out, "  are Go's closest match - a tokio task is its future's state, often smaller")
+	fmt.Fprintln(out, "  than a goroutine, but it can't grow: deep recursion in a task means boxing")
+	fmt.Fprintln(out, "  futures by hand.")
+	return nil
+}
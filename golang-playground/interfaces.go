@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"unsafe"
+)
+
+// This file measures what it actually costs to put values in an interface
+
+func init() {
+	Register(newDemo("interface-boxing", "Boxing small (cached) vs large ints into interface{}", func() {
+		fmt.Fprintln(out, "\nInterface Boxing")
+		interfaceBoxingExample()
+	}).in(categoryInterfaces))
+	Register(newDemo("interface-slice-boxing", "[]interface{} of 1,000 ints vs []int", DemonstrateInterfaceSliceBoxing).in(categoryInterfaces))
+	Register(newDemo("pointer-interface-layout", "*interface{} vs interface{} holding a *User", DemonstratePointerInterfaceLayout).in(categoryInterfaces))
+	Register(newDemo("pointer-interface", "*User in an interface is free, User is boxed", DemonstratePointerInterface).in(categoryInterfaces))
+	Register(newDemo("boxing-costs", "Allocations per conversion to any and error, value kind by kind", DemonstrateBoxingCosts).in(categoryInterfaces))
+}
+
+const boxingIterations = 256
+
+// Box values 0..255 - the runtime serves these from its static small-integer
+// table (runtime.staticuint64s), so no heap allocation is needed
+func boxSmallInts() {
+	for i := 0; i < boxingIterations; i++ {
+		globalInterface = i
+	}
+}
+
+// Box values 256..511 - outside the cached range, every assignment
+// has to allocate a fresh 8-byte box on the heap
+func boxLargeInts() {
+	for i := boxingIterations; i < 2*boxingIterations; i++ {
+		globalInterface = i
+	}
+}
+
+// Interface boxing - when does "everything is a pointer under an interface" cost memory?
+func interfaceBoxingExample() {
+	small := TrackMemory(fmt.Sprintf("Box ints 0..%d into interface{}", boxingIterations-1), func() {
+		boxSmallInts()
+	})
+	large := TrackMemory(fmt.Sprintf("Box ints %d..%d into interface{}", boxingIterations, 2*boxingIterations-1), func() {
+		boxLargeInts()
+	})
+
+	fmt.Fprintf(out, "\n  Small ints: %d mallocs for %d assignments (served from the static cache)\n", small.Mallocs, boxingIterations)
+	fmt.Fprintf(out, "  Large ints: %d mallocs for %d assignments (one heap box each)\n", large.Mallocs, boxingIterations)
+	fmt.Fprintln(out, "  An interface holds (type, pointer) - a non-pointer value must live somewhere,")
+	fmt.Fprintln(out, "  so Go boxes it on the heap unless the runtime already has a copy")
+	globalInterface = nil
+}
+
+// Existing values to convert - created up front so only the conversion is measured
+var (
+	ifaceUserPtr   = &User{Name: "Dave", Age: 35}
+	ifaceUserValue = User{Name: "Dave", Age: 35}
+)
+
+// The pointer IS the interface's data word - nothing to copy
+func storePointerInInterface() {
+	globalInterface = ifaceUserPtr
+}
+
+// The 24-byte User doesn't fit in one word - it is copied into a heap box
+func storeValueInInterface() {
+	globalInterface = ifaceUserValue
+}
+
+// Demonstrate that a pointer in an interface is free while a struct value is boxed
+func DemonstratePointerInterface() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "POINTER VS VALUE IN AN INTERFACE")
+	fmt.Fprintln(out, "============================================================")
+
+	fmt.Fprintf(out, "  globalInterface = userPtr (*User): allocates=%t (%.0f allocs/op)\n",
+		DidAllocate(storePointerInInterface), MeasureAllocs("storePointerInInterface", storePointerInInterface))
+	fmt.Fprintf(out, "  globalInterface = user (User):     allocates=%t (%.0f allocs/op)\n",
+		DidAllocate(storeValueInInterface), MeasureAllocs("storeValueInInterface", storeValueInInterface))
+
+	fmt.Fprintln(out, "\n  An empty interface (eface) is two words: [type pointer | data pointer]")
+	fmt.Fprintln(out, "  (a non-empty iface swaps the type for an itab: type + method table)")
+	fmt.Fprintln(out, "  - *User: the pointer goes straight into the data word, no allocation")
+	fmt.Fprintln(out, "  - User:  the data word must point at a copy, so the value is boxed")
+	fmt.Fprintln(out, "  So it's not \"interfaces escape\" - it's \"non-pointer values get boxed\"")
+	globalInterface = nil
+}
+
+// Sink for the pointer-to-interface case
+var ifacePtrSink *interface{}
+
+// An interface holding a pointer: two words, the data word IS the pointer
+func interfaceHoldingPointer() {
+	var i interface{} = ifaceUserPtr
+	globalInterface = i
+}
+
+// A pointer to an interface: the interface itself must now live somewhere
+// addressable and outlive this frame, so its two words move to the heap
+func pointerToInterface() {
+	var i interface{} = ifaceUserPtr
+	ifacePtrSink = &i
+}
+
+// Demonstrate why you almost never want *interface{}
+func DemonstratePointerInterfaceLayout() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "*interface{} VS interface{} HOLDING *User")
+	fmt.Fprintln(out, "============================================================")
+
+	var i interface{} = ifaceUserPtr
+	p := &i
+	fmt.Fprintf(out, "  unsafe.Sizeof(interface{}):  %d bytes (type word + data word)\n", unsafe.Sizeof(i))
+	fmt.Fprintf(out, "  unsafe.Sizeof(*interface{}): %d bytes (one pointer - to the 16 above)\n", unsafe.Sizeof(p))
+	fmt.Fprintf(out, "  interface{} = *User:   %.0f allocs/op\n", MeasureAllocs("interfaceHoldingPointer", interfaceHoldingPointer))
+	fmt.Fprintf(out, "  &interface{} escaping: %.0f allocs/op\n", MeasureAllocs("pointerToInterface", pointerToInterface))
+
+	fmt.Fprintln(out, "\n  An interface is already a (type, pointer) pair - it can hold a *User")
+	fmt.Fprintln(out, "  directly, and methods with pointer receivers work through it.")
+	fmt.Fprintln(out, "  *interface{} adds a second indirection: reaching the User is now")
+	fmt.Fprintln(out, "  pointer -> eface -> User, and the eface itself has to be heap-allocated.")
+	fmt.Fprintln(out, "  Needing *interface{} usually means you wanted interface{} holding a *T.")
+	globalInterface = nil
+	ifacePtrSink = nil
+}
+
+const interfaceSliceLen = 1000
+
+// Sinks keeping both slices reachable until measured
+var (
+	ifaceSliceSink []interface{}
+	intSliceSink   []int
+)
+
+// Each element is a 16-byte eface plus, beyond the small-int cache, an 8-byte box
+func buildInterfaceSlice() {
+	s := make([]interface{}, interfaceSliceLen)
+	for i := range s {
+		s[i] = boxingIterations + i // start past 255 so nothing comes from the cache
+	}
+	ifaceSliceSink = s
+}
+
+// Each element is the 8-byte int itself, stored inline
+func buildIntSlice() {
+	s := make([]int, interfaceSliceLen)
+	for i := range s {
+		s[i] = boxingIterations + i
+	}
+	intSliceSink = s
+}
+
+// Demonstrate the per-element cost of a heterogeneous []interface{}
+func DemonstrateInterfaceSliceBoxing() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "[]interface{} VS []int")
+	fmt.Fprintln(out, "============================================================")
+
+	ifaces := TrackMemory(fmt.Sprintf("[]interface{} of %d ints", interfaceSliceLen), func() {
+		buildInterfaceSlice()
+	})
+	ints := TrackMemory(fmt.Sprintf("[]int of %d ints", interfaceSliceLen), func() {
+		buildIntSlice()
+	})
+
+	fmt.Fprintf(out, "\n  Backing arrays: %d bytes ([]interface{}, 16 per element) vs %d bytes ([]int, 8 per element)\n",
+		interfaceSliceLen*int(unsafe.Sizeof(interface{}(nil))), interfaceSliceLen*int(unsafe.Sizeof(int(0))))
+	fmt.Fprintf(out, "  Mallocs: %d vs %d - one box per element on top of the array\n", ifaces.Mallocs, ints.Mallocs)
+	if ints.TotalAlloc > 0 {
+		fmt.Fprintf(out, "  The interface slice costs %.1fx the bytes\n", float64(ifaces.TotalAlloc)/float64(ints.TotalAlloc))
+	}
+	fmt.Fprintln(out, "  Generics ([]T with T = int) keep the compact layout; Rust's Vec<i64> vs")
+	fmt.Fprintln(out, "  Vec<Box<dyn Any>> is the same tradeoff.")
+	ifaceSliceSink = nil
+	intSliceSink = nil
+}
+
+// Values to convert, read from variables so the compiler can't box them
+// as static data the way it does constants
+var (
+	boxSmallInt  = 42
+	boxLargeInt  = 1000
+	boxByte      = byte('x')
+	boxFloat     = 3.5
+	boxString    = string([]byte("dynamic"))
+	boxEmpty     = struct{}{}
+	boxMap       = map[string]int{}
+	boxChan      = make(chan int)
+	boxFunc      = func() {}
+	boxCodeError = codeError{code: 404}
+	boxPathError = &pathError{path: "/tmp"}
+	intSink      int
+	errorSink    error
+)
+
+// codeError is an error with a value receiver: a non-pointer value, boxed
+// like any other when stored in an error
+type codeError struct{ code int }
+
+func (e codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+// pathError is an error with a pointer receiver, the usual shape: a
+// *pathError is one word and goes into the interface as is
+type pathError struct{ path string }
+
+func (e *pathError) Error() string { return "bad path " + e.path }
+
+// Demonstrate which conversions to an interface allocate, and why
+func DemonstrateBoxingCosts() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "WHAT BOXING COSTS, VALUE BY VALUE")
+	fmt.Fprintln(out, "============================================================")
+
+	cases := []struct {
+		label, why string
+		fn         func()
+	}{
+		{"int 1000 -> int", "no interface, no box", func() { intSink = boxLargeInt }},
+		{"int 42 -> any", "0..255 served from runtime.staticuint64s", func() { globalInterface = boxSmallInt }},
+		{"int 1000 -> any", "8-byte box", func() { globalInterface = boxLargeInt }},
+		{"byte -> any", "every single-byte value is in the static table", func() { globalInterface = boxByte }},
+		{"float64 -> any", "8-byte box", func() { globalInterface = boxFloat }},
+		{"string -> any", "16-byte box for the header", func() { globalInterface = boxString }},
+		{"struct{} -> any", "zero-size values all share one address", func() { globalInterface = boxEmpty }},
+		{"User -> any", "24-byte box", func() { globalInterface = ifaceUserValue }},
+		{"*User -> any", "pointer-shaped: stored in the data word", func() { globalInterface = ifaceUserPtr }},
+		{"map -> any", "pointer-shaped", func() { globalInterface = boxMap }},
+		{"chan -> any", "pointer-shaped", func() { globalInterface = boxChan }},
+		{"func -> any", "pointer-shaped", func() { globalInterface = boxFunc }},
+		{"codeError -> error", "value receiver: 8-byte box", func() { errorSink = boxCodeError }},
+		{"*pathError -> error", "pointer receiver: pointer-shaped", func() { errorSink = boxPathError }},
+		{"escapesViaInterface()", "\"x escapes\", but x is 42", escapesViaInterface},
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Conversion\tallocs/op\tWhy")
+	for _, c := range cases {
+		fmt.Fprintf(tw, "  %s\t%.0f\t%s\n", c.label, MeasureAllocs(c.label, c.fn), c.why)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(out, "\n  An interface's data word is a pointer. Pointer-shaped values - pointers,")
+	fmt.Fprintln(out, "  maps, channels, funcs, single-pointer structs - fit in it directly. Anything")
+	fmt.Fprintln(out, "  else needs a copy on the heap, unless the runtime already has one: small")
+	fmt.Fprintln(out, "  integers, single bytes, zero-size values, and constants the compiler boxes")
+	fmt.Fprintln(out, "  into read-only data. So -gcflags=-m saying \"escapes to heap\" is where a")
+	fmt.Fprintln(out, "  box may be needed; these counts are whether one was. Errors with pointer")
+	fmt.Fprintln(out, "  receivers cost nothing to return; value-receiver errors pay a box each time.")
+	fmt.Fprintln(out, "  Rust makes the box explicit: Box<dyn Error> allocates, &dyn Error doesn't.")
+	globalInterface, errorSink = nil, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang-playground/schema"
+)
+
+// This file runs each demonstration in a fresh copy of this binary for
+// -isolate. In one process every measurement inherits the heap, the GC
+// pacing and the warmed-up caches of the demos before it; a child process
+// starts from nothing and reports back in the JSON schema.
+
+// Flags that only make sense in the parent: they select the demos, shape
+// the combined output, or write one file the children would fight over
+var parentOnlyFlags = map[string]bool{
+	"isolate": true, "run-one": true, "demo": true, "format": true, "list": true,
+	"quiet": true, "timeline": true, "timeline-interval": true, "trace": true,
+	"compare": true, "compare-rust": true, "quiz": true,
+}
+
+// forwardedFlags is every flag the user set, except the parent's own
+func forwardedFlags() []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if !parentOnlyFlags[f.Name] {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// childArgs forwards the user's flags and asks the child to run just name
+func childArgs(name string) []string {
+	return append(forwardedFlags(), "-run-one="+name)
+}
+
+// runIsolated runs each demonstration in its own child process, echoing
+// the child's narrative and recording its results, observations and
+// timing as if the demo had run here
+func runIsolated(demos []Demonstration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("isolate: %w", err)
+	}
+	for _, d := range demos {
+		var stdout bytes.Buffer
+		cmd := exec.Command(exe, childArgs(d.Name())...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = out // the child's narrative, which -run-one sends to stderr
+
+		if err := inRegion(d.Name(), cmd.Run); err != nil {
+			return fmt.Errorf("%s: child process: %w", d.Name(), err)
+		}
+		report, err := schema.Decode(stdout.Bytes())
+		if err != nil {
+			return fmt.Errorf("%s: child results: %w", d.Name(), err)
+		}
+
+		// The child's own timing, without process start-up
+		var elapsed time.Duration
+		for _, rec := range report.Demos {
+			elapsed += time.Duration(rec.DurationNs)
+		}
+		recorded = append(recorded, report.Results...)
+		observed = append(observed, report.Observations...)
+		addScore(d.Name(), report.Results, elapsed)
+	}
+	return nil
+}
+
+// runOne is the child side of -isolate: run the one demonstration, with
+// its narrative on stderr and a JSON Report on stdout
+func runOne(name string) error {
+	d, err := findDemo(name)
+	if err != nil {
+		return err
+	}
+	out = os.Stderr
+	if *profileDirFlag != "" {
+		if err := enableProfiles(*profileDirFlag); err != nil {
+			return err
+		}
+	}
+	results, err := collectResults(func() error {
+		return runDemos([]Demonstration{d})
+	})
+	if err == nil {
+		err = profileErr
+	}
+	if err != nil {
+		return err
+	}
+	return jsonWriter{os.Stdout}.Write(results)
+}
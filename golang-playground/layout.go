@@ -0,0 +1,261 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unsafe"
+)
+
+// This file breaks down where a struct's memory actually goes
+
+func init() {
+	Register(newDemo("describe-allocation", "Per-field breakdown of a struct's memory footprint", func() {
+		fmt.Fprintln(out, "\n"+"============================================================")
+		fmt.Fprintln(out, "PER-FIELD ALLOCATION ATTRIBUTION")
+		fmt.Fprintln(out, "============================================================")
+		DescribeAllocation(createLargeObject(1))
+		DescribeAllocation(User{Name: "Alice", Age: 30})
+		fmt.Fprintln(out, "\n  LargeObject's 1024-byte Data array dwarfs its 8-byte ID - and unsafe.Sizeof")
+		fmt.Fprintln(out, "  only sees the 24-byte slice header. (Indirect data may also be static,")
+		fmt.Fprintln(out, "  like the \"Alice\" literal, which lives in the binary rather than the heap.)")
+	}).in(categoryLayout))
+	Register(newDemo("embedding", "Embedded User is laid out inline; embedded *User is a pointer hop", DemonstrateEmbedding).in(categoryLayout))
+	Register(newDemo("struct-layout", "Field offsets, alignment and padding, with a tighter field order", DemonstrateStructLayout).in(categoryLayout))
+}
+
+// referencedBytes estimates the memory a field points at beyond its own header.
+// Maps are a lower bound: only len*(key+value), not buckets or spare slots.
+func referencedBytes(v reflect.Value) (uint64, bool) {
+	switch v.Kind() {
+	case reflect.Slice:
+		return uint64(v.Cap()) * uint64(v.Type().Elem().Size()), !v.IsNil()
+	case reflect.String:
+		return uint64(v.Len()), v.Len() > 0
+	case reflect.Map:
+		t := v.Type()
+		return uint64(v.Len()) * uint64(t.Key().Size()+t.Elem().Size()), !v.IsNil()
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0, false
+		}
+		return uint64(v.Type().Elem().Size()), true
+	}
+	return 0, false
+}
+
+// DescribeAllocation prints each exported field of the struct v (or *v) with
+// its inline size and the memory it references, summing to an estimated total.
+// Unlike unsafe.Sizeof, it follows slice, string, map and pointer fields.
+func DescribeAllocation(v interface{}) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		fmt.Fprintf(out, "\n  DescribeAllocation: %T is not a struct\n", v)
+		return
+	}
+
+	t := rv.Type()
+	fmt.Fprintf(out, "\n=== Allocation breakdown: %s ===\n", t)
+	fmt.Fprintf(out, "  %-12s %-10s %8s %10s  %s\n", "Field", "Kind", "Inline", "Referenced", "Indirect")
+
+	total := uint64(t.Size())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		referenced, onHeap := referencedBytes(rv.Field(i))
+		total += referenced
+		fmt.Fprintf(out, "  %-12s %-10s %8d %10d  %t\n",
+			field.Name, field.Type.Kind(), field.Type.Size(), referenced, onHeap)
+	}
+	fmt.Fprintf(out, "  Struct itself: %d bytes, estimated total: %d bytes\n", t.Size(), total)
+}
+
+// Employee embeds User by value - its fields are promoted AND stored inline
+type Employee struct {
+	User
+	Salary int
+}
+
+// flatEmployee spells out the same fields without embedding
+type flatEmployee struct {
+	Name   string
+	Age    int
+	Salary int
+}
+
+// EmployeeRef embeds a *User - promoted fields are reached through a pointer
+type EmployeeRef struct {
+	*User
+	Salary int
+}
+
+// Sinks forcing each construction onto the heap so they can be compared
+var (
+	employeeSink    *Employee
+	flatSink        *flatEmployee
+	employeeRefSink *EmployeeRef
+)
+
+// Demonstrate that struct embedding is composition of layout, not indirection
+func DemonstrateEmbedding() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "STRUCT EMBEDDING LAYOUT")
+	fmt.Fprintln(out, "============================================================")
+
+	var e Employee
+	var f flatEmployee
+	var r EmployeeRef
+	fmt.Fprintf(out, "  %-14s size=%2d  Name@%d Age@%d Salary@%d\n", "Employee",
+		unsafe.Sizeof(e), unsafe.Offsetof(e.Name), unsafe.Offsetof(e.Age), unsafe.Offsetof(e.Salary))
+	fmt.Fprintf(out, "  %-14s size=%2d  Name@%d Age@%d Salary@%d\n", "flatEmployee",
+		unsafe.Sizeof(f), unsafe.Offsetof(f.Name), unsafe.Offsetof(f.Age), unsafe.Offsetof(f.Salary))
+	fmt.Fprintf(out, "  %-14s size=%2d  *User@%d Salary@%d (Name/Age live elsewhere)\n", "EmployeeRef",
+		unsafe.Sizeof(r), unsafe.Offsetof(r.User), unsafe.Offsetof(r.Salary))
+
+	embedded := MeasureAllocs("new Employee", func() {
+		employeeSink = &Employee{User: User{Name: "Erin", Age: 41}, Salary: 100}
+	})
+	flat := MeasureAllocs("new flatEmployee", func() {
+		flatSink = &flatEmployee{Name: "Erin", Age: 41, Salary: 100}
+	})
+	pointer := MeasureAllocs("new EmployeeRef", func() {
+		employeeRefSink = &EmployeeRef{User: &User{Name: "Erin", Age: 41}, Salary: 100}
+	})
+	fmt.Fprintf(out, "\n  Allocations to build one: Employee=%.0f flatEmployee=%.0f EmployeeRef=%.0f\n", embedded, flat, pointer)
+
+	fmt.Fprintln(out, "\n  Embedding a value copies its fields into the outer struct's memory: same")
+	fmt.Fprintln(out, "  offsets, same size, same single allocation as writing the fields out.")
+	fmt.Fprintln(out, "  e.Name is just e.User.Name at a fixed offset - no pointer to follow.")
+	fmt.Fprintln(out, "  Embedding a pointer does add a hop and a second allocation. Rust's")
+	fmt.Fprintln(out, "  composition (a struct field) vs Box<T> field is the same distinction.")
+	employeeSink, flatSink, employeeRefSink = nil, nil, nil
+}
+
+// badlyOrdered alternates small and large fields, so nearly every field
+// is followed by padding
+type badlyOrdered struct {
+	Active  bool
+	ID      int64
+	Admin   bool
+	Score   int32
+	Deleted bool
+}
+
+// sparseRecord mixes pointer, float and small integer fields at random
+type sparseRecord struct {
+	Kind  byte
+	Total float64
+	Count int16
+	Next  *sparseRecord
+	Ratio float32
+	Flag  bool
+}
+
+// fieldLayout is where one field sits in its struct
+type fieldLayout struct {
+	name                string
+	typ                 reflect.Type
+	offset, size, align uintptr
+	padding             uintptr // bytes wasted between this field and the next (or the end)
+}
+
+// structLayout lists t's fields with the padding after each
+func structLayout(t reflect.Type) []fieldLayout {
+	fields := make([]fieldLayout, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		fields[i] = fieldLayout{
+			name:   f.Name,
+			typ:    f.Type,
+			offset: f.Offset,
+			size:   f.Type.Size(),
+			align:  uintptr(f.Type.Align()),
+		}
+	}
+	for i := range fields {
+		next := t.Size()
+		if i+1 < len(fields) {
+			next = fields[i+1].offset
+		}
+		fields[i].padding = next - fields[i].offset - fields[i].size
+	}
+	return fields
+}
+
+// packedSize is the size of a struct holding fields in the given order,
+// laid out the way the compiler does: each field at the next multiple of
+// its alignment, the whole rounded up to the largest alignment
+func packedSize(fields []fieldLayout) uintptr {
+	var offset, maxAlign uintptr = 0, 1
+	for _, f := range fields {
+		offset = (offset + f.align - 1) / f.align * f.align
+		offset += f.size
+		maxAlign = max(maxAlign, f.align)
+	}
+	return (offset + maxAlign - 1) / maxAlign * maxAlign
+}
+
+// LayoutReport prints the memory layout of the struct v (or *v): each
+// field's offset, size, alignment and the padding after it, then a field
+// order that wastes less, if there is one. The compiler never reorders
+// fields, so declaration order is layout order.
+func LayoutReport(v any) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		fmt.Fprintf(out, "\n  LayoutReport: %T is not a struct\n", v)
+		return
+	}
+
+	fields := structLayout(t)
+	fmt.Fprintf(out, "\n=== Layout: %s (size %d, align %d) ===\n", t, t.Size(), t.Align())
+	fmt.Fprintf(out, "  %-10s %-20s %6s %4s %5s %7s\n", "Field", "Type", "Offset", "Size", "Align", "Padding")
+	var wasted uintptr
+	for _, f := range fields {
+		fmt.Fprintf(out, "  %-10s %-20s %6d %4d %5d %7d\n", f.name, f.typ, f.offset, f.size, f.align, f.padding)
+		wasted += f.padding
+	}
+	fmt.Fprintf(out, "  Padding: %d of %d bytes\n", wasted, t.Size())
+
+	// Largest alignment first packs every field against the previous one;
+	// the stable sort keeps declaration order among equal alignments
+	best := slices.Clone(fields)
+	slices.SortStableFunc(best, func(a, b fieldLayout) int { return cmp.Compare(b.align, a.align) })
+	if size := packedSize(best); size < t.Size() {
+		names := make([]string, len(best))
+		for i, f := range best {
+			names[i] = f.name
+		}
+		fmt.Fprintf(out, "  Suggested order: %s -> %d bytes (saves %d)\n", strings.Join(names, ", "), size, t.Size()-size)
+	} else {
+		fmt.Fprintln(out, "  Already optimally ordered")
+	}
+}
+
+// Demonstrate alignment padding and how field order changes struct size
+func DemonstrateStructLayout() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "STRUCT LAYOUT AND PADDING")
+	fmt.Fprintln(out, "============================================================")
+
+	LayoutReport(User{})
+	LayoutReport(LargeObject{})
+	LayoutReport(badlyOrdered{})
+	LayoutReport(sparseRecord{})
+
+	fmt.Fprintln(out, "\n  Every field starts at a multiple of its alignment, and the struct's size")
+	fmt.Fprintln(out, "  is a multiple of its largest alignment, so arrays of it stay aligned.")
+	fmt.Fprintln(out, "  Go keeps declaration order - sort fields largest-alignment first to close")
+	fmt.Fprintln(out, "  the gaps (the fieldalignment analyzer flags structs that could shrink).")
+	fmt.Fprintln(out, "  Rust's default repr is free to reorder fields and does exactly this;")
+	fmt.Fprintln(out, "  #[repr(C)] opts back into declaration order.")
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// This file is about where data sits relative to other data: a slice of
+// values is one contiguous block, a slice of pointers is a block of
+// addresses with the values somewhere else on the heap
+
+func init() {
+	Register(newDemo("soa-vs-aos", "The same particles as a slice of structs vs parallel slices: bytes and iteration", DemonstrateSoAVsAoS).in(categoryLayout))
+	Register(newDemo("user-slices", "[]User vs []*User with a million elements: bytes, objects, iteration, GC", DemonstrateUserSlices).in(categoryLayout))
+}
+
+// How many Users each layout holds, and how many passes iteration is timed over
+const (
+	localityUsers  = 1_000_000
+	localityPasses = 10
+)
+
+// The layouts under test, kept reachable while measured
+var (
+	userValues   []User
+	userPointers []*User
+	ageSumSink   int
+)
+
+// buildUserValues stores every User inline in one backing array
+func buildUserValues() {
+	userValues = make([]User, localityUsers)
+	for i := range userValues {
+		userValues[i] = User{Name: "user", Age: i % 100}
+	}
+}
+
+// buildUserPointers allocates every User on its own and keeps the pointers
+func buildUserPointers() {
+	userPointers = make([]*User, localityUsers)
+	for i := range userPointers {
+		userPointers[i] = &User{Name: "user", Age: i % 100}
+	}
+}
+
+// shuffleUserPointers puts the pointers in random order, as a slice built
+// over time from a churned heap ends up: neighbors no longer adjacent
+func shuffleUserPointers() {
+	rng.Shuffle(len(userPointers), func(i, j int) {
+		userPointers[i], userPointers[j] = userPointers[j], userPointers[i]
+	})
+}
+
+func sumAgeValues(users []User) int {
+	total := 0
+	for i := range users {
+		total += users[i].Age
+	}
+	return total
+}
+
+func sumAgePointers(users []*User) int {
+	total := 0
+	for _, u := range users {
+		total += u.Age
+	}
+	return total
+}
+
+// nsPerElement times localityPasses runs of pass over n elements
+func nsPerElement(n int, pass func()) float64 {
+	start := time.Now()
+	for range localityPasses {
+		pass()
+	}
+	return float64(time.Since(start).Nanoseconds()) / float64(localityPasses*n)
+}
+
+// Demonstrate the []T vs []*T decision on a million Users
+func DemonstrateUserSlices() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "[]User VS []*User: A MILLION ELEMENTS")
+	fmt.Fprintln(out, "============================================================")
+
+	values := MeasureMemory("[]User", buildUserValues)
+	valuesIter := nsPerElement(localityUsers, func() { ageSumSink += sumAgeValues(userValues) })
+	valuesGC, _ := timeGCCycles(scanCycles)
+	userValues = nil
+
+	pointers := MeasureMemory("[]*User", buildUserPointers)
+	pointersIter := nsPerElement(localityUsers, func() { ageSumSink += sumAgePointers(userPointers) })
+	pointersGC, _ := timeGCCycles(scanCycles)
+	shuffleUserPointers()
+	shuffledIter := nsPerElement(localityUsers, func() { ageSumSink += sumAgePointers(userPointers) })
+	userPointers = nil
+
+	fmt.Fprintf(out, "  %d Users of %d bytes each\n\n", localityUsers, unsafe.Sizeof(User{}))
+	fmt.Fprintf(out, "  %-20s %10s %10s %14s %12s\n", "Layout", "Bytes", "Objects", "Iterate (ns)", "GC per cycle")
+	fmt.Fprintf(out, "  %-20s %10s %10d %14.2f %12v\n", "[]User", formatBytes(values.TotalAlloc), values.Mallocs, valuesIter, valuesGC.Round(time.Microsecond))
+	fmt.Fprintf(out, "  %-20s %10s %10d %14.2f %12v\n", "[]*User", formatBytes(pointers.TotalAlloc), pointers.Mallocs, pointersIter, pointersGC.Round(time.Microsecond))
+	fmt.Fprintf(out, "  %-20s %10s %10s %14.2f %12s\n", "[]*User, shuffled", "", "", shuffledIter, "")
+	observe("[]User ns per element", valuesIter, "ns")
+	observe("[]*User ns per element", pointersIter, "ns")
+	observe("[]*User shuffled ns per element", shuffledIter, "ns")
+
+	fmt.Fprintln(out, "\n  []User is one allocation; the Users sit back to back, so iterating")
+	fmt.Fprintln(out, "  streams through memory and the GC has one object to mark (it still reads")
+	fmt.Fprintln(out, "  each Name's pointer). []*User adds a pointer per element and a million")
+	fmt.Fprintln(out, "  objects for the GC to find and mark on every cycle. Allocated in order,")
+	fmt.Fprintln(out, "  the Users still land next to each other and iteration holds up; once the")
+	fmt.Fprintln(out, "  order is scrambled, most elements are a cache miss. Choose []*User when")
+	fmt.Fprintln(out, "  elements must be shared or mutated through other references, or are")
+	fmt.Fprintln(out, "  large and moved around a lot. Rust draws the same line: Vec<User> vs")
+	fmt.Fprintln(out, "  Vec<Box<User>>, minus the collector's share of the cost.")
+}
+
+// A particle stored whole: 25 bytes of fields padded to 32 so the float64s
+// of the next element stay aligned
+type particle struct {
+	X, Y, Z float64
+	Alive   bool
+}
+
+// The same particles as parallel slices, one per field: element i is
+// X[i], Y[i], Z[i], Alive[i], and nothing needs padding
+type particles struct {
+	X, Y, Z []float64
+	Alive   []bool
+}
+
+const soaParticles = 1_000_000
+
+// The two layouts under test, kept reachable while measured
+var (
+	particleAoS     []particle
+	particleSoA     particles
+	particleSumSink float64
+)
+
+// particleAlive marks three particles in four alive, the same in both layouts
+func particleAlive(i int) bool { return i%4 != 0 }
+
+func buildParticleAoS(n int) []particle {
+	ps := make([]particle, n)
+	for i := range ps {
+		ps[i] = particle{X: float64(i), Y: 1, Z: 2, Alive: particleAlive(i)}
+	}
+	return ps
+}
+
+func buildParticleSoA(n int) particles {
+	ps := particles{X: make([]float64, n), Y: make([]float64, n), Z: make([]float64, n), Alive: make([]bool, n)}
+	for i := range n {
+		ps.X[i], ps.Y[i], ps.Z[i], ps.Alive[i] = float64(i), 1, 2, particleAlive(i)
+	}
+	return ps
+}
+
+// sumAliveX reads two fields of each particle: the AoS loop still pulls
+// all 32 bytes through the cache, the SoA loop only the 9 it uses
+func sumAliveXAoS(ps []particle) float64 {
+	total := 0.0
+	for i := range ps {
+		if ps[i].Alive {
+			total += ps[i].X
+		}
+	}
+	return total
+}
+
+func sumAliveXSoA(ps particles) float64 {
+	total := 0.0
+	for i, alive := range ps.Alive {
+		if alive {
+			total += ps.X[i]
+		}
+	}
+	return total
+}
+
+// step touches every field of every particle, where AoS loses its handicap
+func stepAoS(ps []particle) {
+	for i := range ps {
+		if ps[i].Alive {
+			ps[i].X += ps[i].Y
+			ps[i].Y += ps[i].Z
+		}
+	}
+}
+
+func stepSoA(ps particles) {
+	// Resliced to one length so the compiler can drop the bounds checks
+	x, y, z := ps.X, ps.Y[:len(ps.X)], ps.Z[:len(ps.X)]
+	for i, alive := range ps.Alive[:len(x)] {
+		if alive {
+			x[i] += y[i]
+			y[i] += z[i]
+		}
+	}
+}
+
+// Demonstrate array-of-structs vs struct-of-arrays on a million particles
+func DemonstrateSoAVsAoS() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "ARRAY OF STRUCTS VS STRUCT OF ARRAYS")
+	fmt.Fprintln(out, "============================================================")
+
+	aos := MeasureMemory("[]particle", func() { particleAoS = buildParticleAoS(soaParticles) })
+	aosSum := nsPerElement(soaParticles, func() { particleSumSink += sumAliveXAoS(particleAoS) })
+	aosStep := nsPerElement(soaParticles, func() { stepAoS(particleAoS) })
+	particleAoS = nil
+
+	soa := MeasureMemory("particles", func() { particleSoA = buildParticleSoA(soaParticles) })
+	soaSum := nsPerElement(soaParticles, func() { particleSumSink += sumAliveXSoA(particleSoA) })
+	soaStep := nsPerElement(soaParticles, func() { stepSoA(particleSoA) })
+	particleSoA = particles{}
+
+	fmt.Fprintf(out, "  %d particles: X, Y, Z float64 and Alive bool (%d bytes as a struct)\n\n", soaParticles, unsafe.Sizeof(particle{}))
+	fmt.Fprintf(out, "  %-22s %10s %8s %14s %14s\n", "Layout", "Bytes", "Objects", "Sum X (ns)", "Step (ns)")
+	fmt.Fprintf(out, "  %-22s %10s %8d %14.2f %14.2f\n", "AoS []particle", formatBytes(aos.TotalAlloc), aos.Mallocs, aosSum, aosStep)
+	fmt.Fprintf(out, "  %-22s %10s %8d %14.2f %14.2f\n", "SoA particles{X, Y...}", formatBytes(soa.TotalAlloc), soa.Mallocs, soaSum, soaStep)
+	observe("AoS bytes", float64(aos.TotalAlloc), "bytes")
+	observe("SoA bytes", float64(soa.TotalAlloc), "bytes")
+	observe("AoS sum X ns per element", aosSum, "ns")
+	observe("SoA sum X ns per element", soaSum, "ns")
+
+	fmt.Fprintln(out, "\n  Both hold the same data. A struct is laid out field after field and")
+	fmt.Fprintln(out, "  padded to its alignment, so each particle costs 32 bytes for 25 bytes of")
+	fmt.Fprintln(out, "  fields; the parallel slices pack each field densely and need no padding.")
+	fmt.Fprintln(out, "  A loop over one or two fields (Sum X) reads a 64-byte cache line per two")
+	fmt.Fprintln(out, "  particles with AoS, but per eight with SoA, and the hardware prefetcher")
+	fmt.Fprintln(out, "  streams each field slice independently. When a loop touches most fields")
+	fmt.Fprintln(out, "  (Step) the gap narrows: AoS then uses more of what it loads. Go never")
+	fmt.Fprintln(out, "  reorders struct fields; Rust may, to cut padding, but neither converts")
+	fmt.Fprintln(out, "  layouts for you: Vec<Particle> and a struct of Vec<f64>s make the same")
+	fmt.Fprintln(out, "  trade, and in both SoA means keeping the slices in step by hand. See the")
+	fmt.Fprintln(out, "  slice-sharing demo for how each of those slices is a header over an array.")
+}
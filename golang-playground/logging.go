@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// This file routes demonstration progress through log/slog when asked to
+
+// logger receives structured demo events; nil keeps the pretty console output
+var logger *slog.Logger
+
+// SetLogger sends demonstration progress and results to l as structured
+// events. Pass nil to return to the default console output.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+// currentDemo names the demonstration runDemos is executing, for log attributes
+var currentDemo string
+
+// logEvent emits msg with the current demo and phase, if a logger is set
+func logEvent(phase, msg string, attrs ...slog.Attr) {
+	if logger == nil {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String("demo", currentDemo), slog.String("phase", phase)}, attrs...)
+	logger.LogAttrs(context.Background(), slog.LevelInfo, msg, attrs...)
+}
+
+// logResult emits one measurement as a structured event
+func logResult(r MemResult) {
+	logEvent("measure", "memory measured",
+		slog.String("name", r.Name),
+		slog.Uint64("total_alloc_bytes", r.TotalAlloc),
+		slog.Uint64("heap_alloc_bytes", r.HeapAlloc),
+		slog.Uint64("heap_objects", r.HeapObjects),
+		slog.Uint64("mallocs", r.Mallocs),
+		slog.Uint64("frees", r.Frees),
+		slog.Uint64("gc_cycles", uint64(r.GCCycles)),
+		slog.Duration("elapsed", r.Duration),
+	)
+}
@@ -1,41 +1,293 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
 
 func main() {
-	fmt.Println("=== Go Memory Model Playground ===")
-	fmt.Println()
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+var (
+	demoFlag             = flag.String("demo", "", "run only the named demonstration (default: all)")
+	compareRustFlag      = flag.String("compare-rust", "", "compare against Rust results in this JSON `file`")
+	compareFlag          = flag.String("compare", "", "run two demos head to head: `nameA,nameB`")
+	statsFlag            = flag.String("stats", "memstats", "where measurements read counters: memstats (exact, stops the world) or metrics (runtime/metrics)")
+	formatFlag           = flag.String("format", "text", "result output format: "+strings.Join(supportedFormats(), ", "))
+	listFlag             = flag.Bool("list", false, "list the available demonstrations and exit")
+	logFlag              = flag.Bool("log", false, "emit structured demo events (log/slog JSON) to stderr")
+	seedFlag             = flag.Uint64("seed", defaultSeed, "seed for randomized demonstrations")
+	timelineFlag         = flag.String("timeline", "", "write a HeapAlloc time series (CSV) to this `file`")
+	intervalFlag         = flag.Duration("timeline-interval", 10*time.Millisecond, "sampling interval for -timeline")
+	procsFlag            = flag.Int("procs", 0, "set GOMAXPROCS to `N` before running (default: leave unset)")
+	quizFlag             = flag.Bool("quiz", false, "run an interactive does-it-allocate quiz on stdin")
+	verboseFlag          = flag.Bool("verbose", false, "announce each demonstration with its description")
+	quietFlag            = flag.Bool("quiet", false, "suppress the demonstrations' narrative, keep the results")
+	unsafeRacesFlag      = flag.Bool("unsafe-races", false, "also run the racy versions in the data-races and litmus demos (may crash)")
+	goroutineCountsFlag  = flag.String("goroutines", "1000,10000,100000", "comma-separated goroutine counts for the goroutine-footprint demo")
+	litmusRunsFlag       = flag.Int("litmus-runs", 1_000_000, "how many times the litmus demo runs each test (rare reorderings need millions; a few seconds per million)")
+	traceFlag            = flag.String("trace", "", "write a runtime/trace execution trace, one region per demo, to this `file`")
+	profileDirFlag       = flag.String("profile-dir", "", "write heap profiles before and after each tracked function under this `dir`")
+	scavengeIntervalFlag = flag.Duration("scavenge-interval", 250*time.Millisecond, "how often the fragmentation and free-os-memory demos sample the scavenger's progress")
+	noGCFlag             = flag.Bool("no-gc", false, "disable the GC while each measurement runs (debug.SetGCPercent(-1)), restoring it afterwards")
+	isolateFlag          = flag.Bool("isolate", false, "run each demonstration in a fresh child process of this binary")
+	preallocFlag         = flag.Bool("prealloc", false, "preallocate the append-growth demo's slice so append never reallocates")
+	runOneFlag           = flag.String("run-one", "", "run only the named demonstration and print its JSON report (used by -isolate)")
+)
 
-	// Example 1: Stack vs Heap allocation
-	fmt.Println("1. Stack vs Heap Allocation")
-	stackExample()
-	heapExample()
+// defaultSeed keeps randomized demos reproducible unless -seed overrides it
+const defaultSeed = 42
 
-	// Example 2: Pointer sharing (unique to Go - multiple owners!)
-	fmt.Println("\n2. Pointer Sharing (Multiple References)")
-	pointerSharingExample()
+// rng is the only source of randomness demonstrations should use
+var rng = rand.New(rand.NewPCG(defaultSeed, defaultSeed))
 
-	// Example 3: Slice sharing (shared backing array)
-	fmt.Println("\n3. Slice Sharing (Shared Backing Array)")
-	sliceSharingExample()
+func init() {
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage: %s [flags] [list | metrics | gctrace | compare <go.json> <rust.json> | <demo>] [flags]\n\n", os.Args[0])
+		fmt.Fprintln(w, "  list                          list the available demonstrations and exit")
+		fmt.Fprintln(w, "  metrics                       dump every runtime/metrics value with its meaning")
+		fmt.Fprintln(w, "  gctrace                       run the demos (or -demo) under GODEBUG=gctrace=1 and tabulate each GC cycle")
+		fmt.Fprintln(w, "  compare <go.json> <rust.json> print two results files side by side")
+		fmt.Fprintln(w, "  <demo>                        run only that demonstration, same as -demo=<demo>")
+		fmt.Fprintln(w, "\nFlags:")
+		flag.PrintDefaults()
+	}
+
+	Register(newDemo("stack-heap", "A stack variable vs a pointer that escapes to the heap", func() {
+		fmt.Fprintln(out, "\nStack vs Heap Allocation")
+		stackExample()
+		heapExample()
+	}).in(categoryBasics))
+	Register(newDemo("pointer-sharing", "Several pointers sharing one heap value", func() {
+		// Unique to Go - multiple owners!
+		fmt.Fprintln(out, "\nPointer Sharing (Multiple References)")
+		pointerSharingExample()
+	}).in(categoryBasics))
+	Register(newDemo("slice-sharing", "Reslices sharing one backing array", func() {
+		fmt.Fprintln(out, "\nSlice Sharing (Shared Backing Array)")
+		sliceSharingExample()
+	}).in(categoryBasics))
+	// Registered here rather than in memory_tracking.go to keep the basics together
+	Register(newDemo("tracking", "MemStats-based allocation tracking of stack and heap examples", DemonstrateMemoryTracking).in(categoryBasics))
+}
 
-	// Example 4: Escape analysis (what causes heap allocation?)
-	DemonstrateEscapeAnalysis()
+// commandArgs is how many positional arguments each subcommand takes;
+// anything else is a demo name, which takes none
+var commandArgs = map[string]int{
+	"compare": 2,
+}
 
-	// Example 5: Memory tracking (prove it with measurements)
-	DemonstrateMemoryTracking()
+// parseCommand parses the flags and the optional subcommand ("list",
+// "compare" or a demo name) with its arguments, accepting flags on either
+// side of and between them
+func parseCommand() (string, []string, error) {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		return "", nil, nil
+	}
+	command := flag.Arg(0)
+	want := commandArgs[command]
+	var args []string
+	for rest := flag.Args()[1:]; ; rest = flag.Args()[1:] {
+		if err := flag.CommandLine.Parse(rest); err != nil {
+			return "", nil, err
+		}
+		if flag.NArg() == 0 || len(args) == want {
+			break
+		}
+		args = append(args, flag.Arg(0))
+	}
+	if flag.NArg() > 0 {
+		return "", nil, fmt.Errorf("unexpected arguments after %q: %s", command, strings.Join(flag.Args(), " "))
+	}
+	if len(args) != want {
+		return "", nil, fmt.Errorf("%s wants %d arguments, got %d", command, want, len(args))
+	}
+	return command, args, nil
+}
+
+// run executes the selected demonstrations and reports any failure to main
+func run() error {
+	command, args, err := parseCommand()
+	if err != nil {
+		return err
+	}
+
+	if *listFlag || command == "list" {
+		return listDemos(os.Stdout)
+	}
+	if command == "compare" {
+		return CompareFiles(args[0], args[1])
+	}
+	if command == "metrics" {
+		return dumpMetrics(os.Stdout)
+	}
+	if command == "gctrace" {
+		return GCTrace(*demoFlag)
+	}
+	if err := setStatsSource(*statsFlag); err != nil {
+		return err
+	}
+
+	demoName := *demoFlag
+	if command != "" {
+		if demoName != "" && demoName != command {
+			return fmt.Errorf("demo %q given both as -demo=%s and as a subcommand", command, demoName)
+		}
+		demoName = command
+	}
+
+	if *verboseFlag && *quietFlag {
+		return fmt.Errorf("-verbose and -quiet are mutually exclusive")
+	}
+	verbose = *verboseFlag
+	unsafeRaces = *unsafeRacesFlag
+	gcDisabled = *noGCFlag
+
+	if *logFlag {
+		SetLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+
+	rng = rand.New(rand.NewPCG(*seedFlag, *seedFlag))
+
+	if *procsFlag < 0 {
+		return fmt.Errorf("-procs must be positive, got %d", *procsFlag)
+	}
+	if *procsFlag > 0 {
+		runtime.GOMAXPROCS(*procsFlag)
+	}
+
+	if *quizFlag {
+		return runQuiz(os.Stdin, os.Stdout)
+	}
+
+	if *compareRustFlag != "" {
+		return CompareWithRust(*compareRustFlag)
+	}
+
+	if *compareFlag != "" {
+		return CompareDemos(*compareFlag)
+	}
+
+	if *runOneFlag != "" {
+		return runOne(*runOneFlag)
+	}
+
+	demos, err := selectDemos(demoName)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newResultWriter(*formatFlag, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if *formatFlag != "text" {
+		out = os.Stderr
+	}
+	if *quietFlag {
+		out = io.Discard
+	}
+
+	fmt.Fprintln(out, "=== Go Memory Model Playground ===")
+	fmt.Fprintf(out, "GOMAXPROCS: %d\n", runtime.GOMAXPROCS(0))
+	fmt.Fprintf(out, "Seed: %d\n", *seedFlag)
+	fmt.Fprintf(out, "Race detector: %t\n", raceEnabled)
+	fmt.Fprintf(out, "Stats source: %s\n", *statsFlag)
+	if gcDisabled {
+		fmt.Fprintln(out, "GC during measurements: disabled (-no-gc)")
+	}
+	if *isolateFlag {
+		fmt.Fprintln(out, "Isolation: one child process per demonstration")
+	}
+	fmt.Fprintf(out, "Noise floor: %d bytes per measurement (subtracted from results)\n", baselineNoise())
+
+	if *timelineFlag != "" && *intervalFlag <= 0 {
+		return fmt.Errorf("-timeline-interval must be positive, got %v", *intervalFlag)
+	}
+	if *profileDirFlag != "" {
+		if err := enableProfiles(*profileDirFlag); err != nil {
+			return err
+		}
+	}
+
+	var timeline *heapTimeline
+	if *timelineFlag != "" {
+		timeline = startTimeline(*intervalFlag)
+	}
+	stopTrace := func() error { return nil }
+	if *traceFlag != "" {
+		if stopTrace, err = startTrace(*traceFlag); err != nil {
+			return err
+		}
+	}
+
+	results, err := collectResults(func() error {
+		if *isolateFlag {
+			return runIsolated(demos)
+		}
+		return runDemos(demos)
+	})
+	if terr := stopTrace(); terr != nil {
+		if err == nil {
+			err = terr
+		}
+	} else if *traceFlag != "" {
+		fmt.Fprintf(out, "\nWrote an execution trace to %s; open it with go tool trace %s\n", *traceFlag, *traceFlag)
+	}
+
+	if timeline != nil {
+		samples := timeline.Stop()
+		if werr := writeTimeline(*timelineFlag, samples); werr != nil {
+			if err == nil {
+				err = werr
+			}
+		} else {
+			fmt.Fprintf(out, "\nWrote %d HeapAlloc samples to %s\n", len(samples), *timelineFlag)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if profileErr != nil {
+		return profileErr
+	}
+	if profileDir != "" {
+		fmt.Fprintf(out, "\nWrote heap profiles under %s; see what one tracked function allocated with\n", profileDir)
+		fmt.Fprintln(out, "  go tool pprof -sample_index=alloc_space -ignore=runtime/pprof -base <dir>/before.pprof <dir>/after.pprof")
+		fmt.Fprintln(out, "  (-ignore hides the allocations of writing the \"before\" profile itself)")
+	}
+	if err := writer.Write(results); err != nil {
+		return err
+	}
+	if demoName == "" {
+		printScoreboard()
+	}
+	return nil
 }
 
 // Stack allocation - variable stays on stack
 func stackExample() {
 	x := 42 // Lives on stack, automatically cleaned when function returns
-	fmt.Printf("  Stack variable x = %d (allocated on stack)\n", x)
+	fmt.Fprintf(out, "  Stack variable x = %d (allocated on stack)\n", x)
 }
 
 // Heap allocation - variable escapes to heap
 func heapExample() {
 	user := createUser() // Escapes to heap
-	fmt.Printf("  Heap variable user = %+v (allocated on heap, GC will clean up)\n", user)
+	fmt.Fprintf(out, "  Heap variable user = %+v (allocated on heap, GC will clean up)\n", user)
 }
 
 // This function causes escape to heap because we return a pointer
@@ -53,16 +305,16 @@ func pointerSharingExample() {
 	ptr2 := user
 	ptr3 := user
 
-	fmt.Printf("  Original: %p (ptr at %p) -> %+v\n", user, &user, *user)
-	fmt.Printf("  Ptr1:     %p (ptr at %p) -> %+v\n", ptr1, &ptr1, *ptr1)
-	fmt.Printf("  Ptr2:     %p (ptr at %p) -> %+v\n", ptr2, &ptr2, *ptr2)
-	fmt.Printf("  Ptr3:     %p (ptr at %p) -> %+v\n", ptr3, &ptr3, *ptr3)
-	fmt.Println("  All pointers share the same heap memory!")
-	fmt.Println("  GC will clean up when all references are gone")
+	fmt.Fprintf(out, "  Original: %p (ptr at %p) -> %+v\n", user, &user, *user)
+	fmt.Fprintf(out, "  Ptr1:     %p (ptr at %p) -> %+v\n", ptr1, &ptr1, *ptr1)
+	fmt.Fprintf(out, "  Ptr2:     %p (ptr at %p) -> %+v\n", ptr2, &ptr2, *ptr2)
+	fmt.Fprintf(out, "  Ptr3:     %p (ptr at %p) -> %+v\n", ptr3, &ptr3, *ptr3)
+	fmt.Fprintln(out, "  All pointers share the same heap memory!")
+	fmt.Fprintln(out, "  GC will clean up when all references are gone")
 
 	// Modify through one pointer, affects all
 	ptr1.Age = 26
-	fmt.Printf("  After modification via ptr1: %+v\n", *user)
+	fmt.Fprintf(out, "  After modification via ptr1: %+v\n", *user)
 }
 
 // Slices can share the same backing array
@@ -71,18 +323,21 @@ func sliceSharingExample() {
 	slice1 := original[1:4] // Shares backing array
 	slice2 := original[2:]  // Also shares backing array
 
-	fmt.Printf("  Original: %v (len=%d, cap=%d)\n", original, len(original), cap(original))
-	fmt.Printf("  Slice1:   %v (len=%d, cap=%d)\n", slice1, len(slice1), cap(slice1))
-	fmt.Printf("  Slice2:   %v (len=%d, cap=%d)\n", slice2, len(slice2), cap(slice2))
+	fmt.Fprintf(out, "  Original: %v (len=%d, cap=%d)\n", original, len(original), cap(original))
+	fmt.Fprintf(out, "  Slice1:   %v (len=%d, cap=%d)\n", slice1, len(slice1), cap(slice1))
+	fmt.Fprintf(out, "  Slice2:   %v (len=%d, cap=%d)\n", slice2, len(slice2), cap(slice2))
 
 	// Modify through slice1
 	slice1[1] = 99
 
-	fmt.Println("\n  After modifying slice1[1] = 99:")
-	fmt.Printf("  Original: %v (affected!)\n", original)
-	fmt.Printf("  Slice1:   %v\n", slice1)
-	fmt.Printf("  Slice2:   %v (also affected!)\n", slice2)
-	fmt.Println("  All slices share the same backing array on heap")
+	fmt.Fprintln(out, "\n  After modifying slice1[1] = 99:")
+	fmt.Fprintf(out, "  Original: %v (affected!)\n", original)
+	fmt.Fprintf(out, "  Slice1:   %v\n", slice1)
+	fmt.Fprintf(out, "  Slice2:   %v (also affected!)\n", slice2)
+	fmt.Fprintln(out, "  All slices share the same backing array on heap")
+	fmt.Fprintln(out, "  ...until an append outgrows it and copies to a new one (see append-growth)")
+	observe("original[2] after slice1[1] = 99", float64(original[2]), "value")
+	observe("slice2[0] after slice1[1] = 99", float64(slice2[0]), "value")
 }
 
 // Types
@@ -91,7 +346,27 @@ type User struct {
 	Age  int
 }
 
+// Describe returns a one-line summary, making User satisfy small interfaces
+func (u User) Describe() string {
+	return fmt.Sprintf("%s (%d)", u.Name, u.Age)
+}
+
 type LargeObject struct {
 	ID   int
 	Data []byte
 }
+
+// The same method with each receiver kind, kept out of line so the copy
+// a value receiver implies actually happens (see BenchmarkValueReceiver)
+
+//go:noinline
+func (u User) ageByValue() int { return u.Age }
+
+//go:noinline
+func (u *User) ageByPointer() int { return u.Age }
+
+//go:noinline
+func (o LargeObject) sizeByValue() int { return len(o.Data) }
+
+//go:noinline
+func (o *LargeObject) sizeByPointer() int { return len(o.Data) }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"testing"
+)
+
+// Mirrors pointerSharingExample: every pointer must alias the same User
+func TestPointerSharingMutatesSharedState(t *testing.T) {
+	user := &User{Name: "Bob", Age: 25}
+
+	ptr1 := user
+	ptr2 := user
+	ptr3 := user
+
+	ptr1.Age = 26
+
+	for i, p := range []*User{user, ptr2, ptr3} {
+		if p != ptr1 {
+			t.Fatalf("pointer %d = %p, want %p (same address)", i, p, ptr1)
+		}
+		if p.Age != 26 {
+			t.Errorf("pointer %d sees Age = %d, want 26", i, p.Age)
+		}
+	}
+}
+
+// The stack-only example must never start allocating
+func TestStackOnlyAllocationStaysWithinBudget(t *testing.T) {
+	defer func(w io.Writer) { out = w }(out)
+	out = io.Discard
+
+	if err := TrackMemoryBudget("Stack Only", 64, stackOnlyAllocation); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTrackMemoryBudgetReportsOverrun(t *testing.T) {
+	defer func(w io.Writer) { out = w }(out)
+	out = io.Discard
+
+	err := TrackMemoryBudget("Large Allocation", 1024, largeAllocation)
+	if err == nil {
+		t.Fatal("TrackMemoryBudget(1MB slice, 1024) = nil, want budget error")
+	}
+}
+
+// Mirrors sliceSharingExample: reslicing copies a slice header (pointer,
+// len, cap) and never the elements, so it must not allocate
+func TestReslicingDoesNotAllocate(t *testing.T) {
+	// The literal is the example's one allocation; build it outside the
+	// measured function so only the reslices are counted
+	original := []int{1, 2, 3, 4, 5}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		slice1 := original[1:4] // header pointing at original[1], no new array
+		slice2 := original[2:]  // header pointing at original[2], no new array
+		slice1[1] = 99          // writes original[2], visible through slice2[0]
+		sliceSink = slice2
+	})
+	if allocs != 0 {
+		t.Errorf("reslicing allocated %.0f times per run, want 0", allocs)
+	}
+	if sliceSink[0] != 99 || original[2] != 99 {
+		t.Errorf("write through slice1 not shared: original = %v, slice2 = %v", original, sliceSink)
+	}
+	sliceSink = nil
+}
+
+// ExpectAllocs fails t unless fn averages exactly n heap allocations per run
+func ExpectAllocs(t testing.TB, n float64, fn func()) {
+	t.Helper()
+	if got := testing.AllocsPerRun(100, fn); got != n {
+		t.Errorf("allocs per run = %.1f, want %.0f", got, n)
+	}
+}
+
+// The escape catalog's claims, checked against the compiler in use
+func TestEscapeCatalogAllocations(t *testing.T) {
+	tests := []struct {
+		name   string
+		allocs float64
+		fn     func()
+	}{
+		{"noEscape", 0, noEscape},
+		{"stackStructAllocation", 0, stackStructAllocation},
+		// Inlined, but the pointer is stored in a global: x must move to the heap
+		{"escapesViaReturn", 1, func() { globalPtr = escapesViaReturn() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ExpectAllocs(t, tt.allocs, tt.fn)
+		})
+	}
+	globalPtr = nil
+}
+
+// Every byte of message i is byte(i), so the consumer's sum is known. Under
+// go test -race (make race) this also checks that neither side touches a
+// buffer after handing it over.
+func TestOwnershipTransferIsRaceFree(t *testing.T) {
+	want := 0
+	for i := range transferMessages {
+		want += int(byte(i)) * transferBufferSize
+	}
+	if got := transferOwnership(transferMessages); got != want {
+		t.Errorf("transferOwnership(%d) = %d, want %d", transferMessages, got, want)
+	}
+}
+
+// The registry runs category by category with the basics first, and every
+// demo is filed under a category
+func TestRegistryOrderedByCategory(t *testing.T) {
+	if !slices.IsSortedFunc(registry, func(a, b Demonstration) int { return cmp.Compare(demoRank(a), demoRank(b)) }) {
+		t.Fatalf("registry not ordered by category: %v", demoNames())
+	}
+	for _, d := range registry {
+		if demoRank(d) == categoryNone.rank() {
+			t.Errorf("demo %q has no category", d.Name())
+		}
+	}
+	basics := []string{"stack-heap", "pointer-sharing", "slice-sharing", "tracking"}
+	if got := demoNames()[:len(basics)]; !slices.Equal(got, basics) {
+		t.Errorf("registry starts with %v, want %v", got, basics)
+	}
+}
+
+// A diagnostic about something else in the function - here the slice the
+// subject is appended to - must not decide the subject's verdict
+func TestVerifyEscapesMatchesSubjectOnly(t *testing.T) {
+	spans := []funcSpan{{name: "noEscapeSliceOfValues", start: 10, end: 20}}
+	escapes := map[int][]string{
+		12: {"append escapes to heap", "u escapes to heap in noEscapeSliceOfValues"},
+	}
+	verdicts, err := verifyEscapes(spans, escapes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := verdicts[0]; v.reported {
+		t.Fatalf("%s reported as escaping from %q, want only %q to count", v.name, v.diagnostics, v.subject)
+	}
+
+	escapes[15] = []string{"make([]User, 0, 8) escapes to heap in noEscapeSliceOfValues"}
+	if verdicts, _ := verifyEscapes(spans, escapes); !verdicts[0].reported {
+		t.Fatalf("diagnostics about the subject itself were not counted")
+	}
+}
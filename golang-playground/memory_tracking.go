@@ -1,39 +1,393 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-playground/schema"
 )
 
-// MemStats helper to track memory allocations
+// MemStats helper to track memory allocations: the counters before and
+// after, read from the -stats source (see metrics.go)
 type MemStats struct {
-	Before runtime.MemStats
-	After  runtime.MemStats
+	Before memSnapshot
+	After  memSnapshot
+}
+
+// MemResult holds the allocation deltas measured around a single function.
+// Its layout is the shared results schema, so the Rust playground's
+// measurements decode into the same type.
+type MemResult = schema.Result
+
+// TrackMemoryResult is what MeasureMemory returns for programmatic use;
+// it is MemResult under the name callers of TrackMemory look for
+type TrackMemoryResult = MemResult
+
+// recorded accumulates every MemResult produced by TrackMemory
+var recorded []MemResult
+
+// collectResults runs fn and returns the MemResults tracked while it ran
+func collectResults(fn func() error) ([]MemResult, error) {
+	start := len(recorded)
+	err := fn()
+	return recorded[start:], err
+}
+
+// TrackMemory runs fn, prints its allocation deltas and returns them.
+// With -profile-dir set it also writes heap profiles around fn.
+func TrackMemory(name string, fn func()) MemResult {
+	var result MemResult
+	profiles := profiled(name, func() {
+		result = MeasureMemory(name, fn)
+	})
+
+	if logger == nil {
+		fmt.Fprintf(out, "\n=== Memory Tracking: %s ===\n", name)
+		fmt.Fprintf(out, "  Total allocated:     %d bytes\n", result.TotalAlloc)
+		fmt.Fprintf(out, "  Heap allocated:      %d bytes\n", result.HeapAlloc)
+		fmt.Fprintf(out, "  Heap objects added:  %d\n", result.HeapObjects)
+		fmt.Fprintf(out, "  Mallocs:             %d\n", result.Mallocs)
+		fmt.Fprintf(out, "  Frees:               %d\n", result.Frees)
+		fmt.Fprintf(out, "  GC cycles:           %d (%s)\n", result.GCCycles, gcNote(result.GCCycles))
+		fmt.Fprintf(out, "  Elapsed:             %v\n", result.Duration)
+		// Read after the measurement: reading /proc allocates
+		if mem, err := ProcessMemory(); err == nil {
+			fmt.Fprintf(out, "  Process RSS / VMS:   %s / %s (the OS's view, whole process)\n", formatBytes(mem.RSS), formatBytes(mem.VMS))
+		}
+		if profiles != "" {
+			fmt.Fprintf(out, "  Heap profiles:       %s\n", profiles)
+		}
+	}
+
+	record(result)
+	return result
 }
 
-func TrackMemory(name string, fn func()) {
+// MeasureMemory runs fn and returns its allocation deltas without printing
+// or recording them - the building block for TrackMemory and comparisons
+func MeasureMemory(name string, fn func()) TrackMemoryResult {
 	var m MemStats
 
 	// Force GC to get clean baseline
 	runtime.GC()
-	runtime.ReadMemStats(&m.Before)
+	defer pauseGC()()
+	readSnapshot(&m.Before)
 
-	// Run the function
+	// Run the function, timing only fn itself
+	start := time.Now()
 	fn()
+	elapsed := time.Since(start)
 
 	// Read memory stats after
-	runtime.ReadMemStats(&m.After)
+	readSnapshot(&m.After)
+
+	result := m.diff(name)
+	result.Duration = elapsed
+	subtractNoise(&result, baselineNoise())
+	return result
+}
 
-	// Calculate differences
-	allocDiff := m.After.TotalAlloc - m.Before.TotalAlloc
-	heapAllocDiff := m.After.HeapAlloc - m.Before.HeapAlloc
-	heapObjects := m.After.HeapObjects - m.Before.HeapObjects
+// gcDisabled turns the collector off while each measurement runs, so no
+// collection mid-example frees memory the HeapAlloc delta should count; -no-gc sets it
+var gcDisabled bool
+
+// pauseGC disables the collector when gcDisabled is set and returns the
+// function restoring the previous GOGC. A GOMEMLIMIT still forces a GC.
+func pauseGC() (restore func()) {
+	if !gcDisabled {
+		return func() {}
+	}
+	old := debug.SetGCPercent(-1)
+	return func() { debug.SetGCPercent(old) }
+}
+
+// gcNote says what cycles GC cycles during a measurement mean for its deltas
+func gcNote(cycles uint32) string {
+	switch {
+	case cycles > 0 && gcDisabled:
+		return "forced despite -no-gc, by GOMEMLIMIT; Heap allocated excludes what it freed"
+	case cycles > 0:
+		return "ran mid-measurement; Heap allocated excludes what it freed"
+	case gcDisabled:
+		return "GC disabled with -no-gc"
+	}
+	return "none ran"
+}
+
+// How many empty measurements baselineNoise takes the minimum of
+const noiseSamples = 5
+
+var (
+	noiseOnce  sync.Once
+	noiseFloor uint64
+)
+
+// baselineNoise is the TotalAlloc an empty function appears to allocate
+// when measured the way MeasureMemory does - the measurement's own
+// resolution. It is calibrated once, keeping the smallest of a few runs
+// so a stray background allocation doesn't inflate it.
+func baselineNoise() uint64 {
+	noiseOnce.Do(func() {
+		var m MemStats
+		for i := 0; i < noiseSamples; i++ {
+			runtime.GC()
+			readSnapshot(&m.Before)
+			start := time.Now()
+			func() {}()
+			_ = time.Since(start)
+			readSnapshot(&m.After)
+
+			apparent := m.After.TotalAlloc - m.Before.TotalAlloc
+			if i == 0 || apparent < noiseFloor {
+				noiseFloor = apparent
+			}
+		}
+	})
+	return noiseFloor
+}
 
-	fmt.Printf("\n=== Memory Tracking: %s ===\n", name)
-	fmt.Printf("  Total allocated:     %d bytes\n", allocDiff)
-	fmt.Printf("  Heap allocated:      %d bytes\n", heapAllocDiff)
-	fmt.Printf("  Heap objects added:  %d\n", heapObjects)
-	fmt.Printf("  Mallocs:             %d\n", m.After.Mallocs-m.Before.Mallocs)
+// subtractNoise removes the calibrated noise floor from r's byte counters,
+// stopping at zero rather than wrapping around
+func subtractNoise(r *MemResult, noise uint64) {
+	r.TotalAlloc -= min(r.TotalAlloc, noise)
+	r.HeapAlloc -= min(r.HeapAlloc, noise)
+}
+
+// MeasureResult is TrackMemory for functions that produce a value: it
+// returns fn's result alongside the allocations made while computing it
+func MeasureResult[T any](name string, fn func() T) (T, MemResult) {
+	var value T
+	result := TrackMemory(name, func() {
+		value = fn()
+	})
+	return value, result
+}
+
+// TrackMemoryBudget runs TrackMemory and fails if fn allocated more than
+// maxBytes in total - a guard against code that starts escaping unexpectedly
+func TrackMemoryBudget(name string, maxBytes uint64, fn func()) error {
+	result := TrackMemory(name, fn)
+	if result.TotalAlloc > maxBytes {
+		return fmt.Errorf("%s: allocated %d bytes, budget is %d bytes", name, result.TotalAlloc, maxBytes)
+	}
+	return nil
+}
+
+// How many runs TrackMemoryN makes and throws away before measuring: the
+// first calls pay for lazy initialization, pool fills and map growth
+const warmupRuns = 2
+
+// Distribution summarizes one counter over TrackMemoryN's measured runs
+type Distribution struct {
+	Min, Median, Mean, P95 float64
+}
+
+// RepeatedResult is what TrackMemoryN returns: how the bytes and objects
+// allocated per run were spread over its runs
+type RepeatedResult struct {
+	Name    string
+	Runs    int
+	Bytes   Distribution
+	Objects Distribution
+}
+
+// TrackMemoryN measures fn n times after warmupRuns discarded runs and
+// prints min/median/mean/p95 of the bytes and objects each run allocated.
+// The run with the median byte count is recorded for the -format writers.
+func TrackMemoryN(name string, n int, fn func()) RepeatedResult {
+	n = max(n, 1)
+	for range warmupRuns {
+		MeasureMemory(name, fn)
+	}
+	runs := make([]MemResult, n)
+	bytes := make([]float64, n)
+	objects := make([]float64, n)
+	for i := range runs {
+		runs[i] = MeasureMemory(name, fn)
+		bytes[i] = float64(runs[i].TotalAlloc)
+		objects[i] = float64(runs[i].Mallocs)
+	}
+	result := RepeatedResult{Name: name, Runs: n, Bytes: distribution(bytes), Objects: distribution(objects)}
+
+	if logger == nil {
+		fmt.Fprintf(out, "\n=== Memory Tracking: %s (%d runs, %d warmup discarded) ===\n", name, n, warmupRuns)
+		fmt.Fprintf(out, "  %-9s %12s %12s %12s %12s\n", "", "min", "median", "mean", "p95")
+		for _, row := range []struct {
+			label string
+			d     Distribution
+		}{{"Bytes", result.Bytes}, {"Objects", result.Objects}} {
+			fmt.Fprintf(out, "  %-9s %12.0f %12.0f %12.1f %12.0f\n", row.label, row.d.Min, row.d.Median, row.d.Mean, row.d.P95)
+		}
+	}
+
+	slices.SortFunc(runs, func(a, b MemResult) int { return cmp.Compare(a.TotalAlloc, b.TotalAlloc) })
+	record(runs[(n-1)/2])
+	return result
+}
+
+// distribution computes the summary of values, sorting them in place.
+// P95 is the nearest-rank percentile: a value that actually occurred.
+func distribution(values []float64) Distribution {
+	slices.Sort(values)
+	n := len(values)
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	median := values[n/2]
+	if n%2 == 0 {
+		median = (values[n/2-1] + values[n/2]) / 2
+	}
+	return Distribution{
+		Min:    values[0],
+		Median: median,
+		Mean:   sum / float64(n),
+		P95:    values[(95*n+99)/100-1],
+	}
+}
+
+// record keeps result for the -format writers and logs it if a logger is set
+func record(result MemResult) {
+	recorded = append(recorded, result)
+	logResult(result)
+}
+
+// diff calculates the differences between the Before and After snapshots
+func (m *MemStats) diff(name string) MemResult {
+	return MemResult{
+		Name:        name,
+		TotalAlloc:  m.After.TotalAlloc - m.Before.TotalAlloc,
+		HeapAlloc:   m.After.HeapAlloc - m.Before.HeapAlloc,
+		HeapObjects: m.After.HeapObjects - m.Before.HeapObjects,
+		Mallocs:     m.After.Mallocs - m.Before.Mallocs,
+		Frees:       m.After.Frees - m.Before.Frees,
+		GCCycles:    m.After.NumGC - m.Before.NumGC,
+	}
+}
+
+// MemTracker records named memory checkpoints through a longer workflow
+type MemTracker struct {
+	checkpoints []checkpoint
+}
+
+// checkpoint keeps only the counters Report needs, so recording one
+// allocates a few dozen bytes rather than a whole snapshot
+type checkpoint struct {
+	name       string
+	totalAlloc uint64
+	mallocs    uint64
+}
+
+// Checkpoint records the current memory stats under name
+func (t *MemTracker) Checkpoint(name string) {
+	var m memSnapshot
+	readSnapshot(&m)
+	t.checkpoints = append(t.checkpoints, checkpoint{name: name, totalAlloc: m.TotalAlloc, mallocs: m.Mallocs})
+}
+
+// Report prints the allocations made between each pair of consecutive
+// checkpoints and names the stage that allocated the most
+func (t *MemTracker) Report() {
+	fmt.Fprintln(out, "\n=== Memory Checkpoints ===")
+	if len(t.checkpoints) < 2 {
+		fmt.Fprintln(out, "  Need at least two checkpoints to report a stage")
+		return
+	}
+
+	var heaviest string
+	var heaviestBytes uint64
+	for i := 1; i < len(t.checkpoints); i++ {
+		prev, cur := t.checkpoints[i-1], t.checkpoints[i]
+		stage := prev.name + " -> " + cur.name
+		allocated := cur.totalAlloc - prev.totalAlloc
+		fmt.Fprintf(out, "  %-30s %10d bytes  %6d mallocs\n", stage, allocated, cur.mallocs-prev.mallocs)
+		if allocated > heaviestBytes {
+			heaviest, heaviestBytes = stage, allocated
+		}
+	}
+	if heaviest != "" {
+		fmt.Fprintf(out, "  Most allocation: %s (%d bytes)\n", heaviest, heaviestBytes)
+	}
+}
+
+// How many calls AllocsPerRun averages over
+const allocsPerRunIterations = 100
+
+// AllocsPerRun reports the average number of heap allocations per call of
+// fn. testing.AllocsPerRun reads ReadMemStats whatever -stats says, which
+// is what makes one allocation per call visible.
+func AllocsPerRun(fn func()) float64 {
+	return testing.AllocsPerRun(allocsPerRunIterations, fn)
+}
+
+// DidAllocate reports whether fn performs any heap allocation
+func DidAllocate(fn func()) bool {
+	return AllocsPerRun(fn) > 0
+}
+
+// How often the peak-heap sampler reads HeapAlloc
+const peakSampleInterval = time.Millisecond
+
+// MeasureMemoryCtx measures fn like TrackMemory, while a background sampler
+// records the peak HeapAlloc reached during the run. The sampler stops as
+// soon as ctx is cancelled; fn receives ctx and should return promptly too.
+// A cancelled run returns what was measured so far with Truncated set.
+func MeasureMemoryCtx(ctx context.Context, name string, fn func(context.Context)) MemResult {
+	var m MemStats
+
+	runtime.GC()
+	defer pauseGC()()
+	readSnapshot(&m.Before)
+
+	peak := make(chan uint64, 1)
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(peakSampleInterval)
+		defer ticker.Stop()
+
+		var highest uint64
+		var stats memSnapshot
+		for {
+			select {
+			case <-ticker.C:
+				readSnapshot(&stats)
+				if stats.HeapAlloc > highest {
+					highest = stats.HeapAlloc
+				}
+			case <-ctx.Done():
+				peak <- highest
+				return
+			case <-stop:
+				peak <- highest
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	fn(ctx)
+	elapsed := time.Since(start)
+	close(stop)
+	sampled := <-peak // the sampler is done reading before the final snapshot
+	readSnapshot(&m.After)
+
+	result := m.diff(name)
+	result.Duration = elapsed
+	subtractNoise(&result, baselineNoise())
+	if highest := max(sampled, m.After.HeapAlloc); highest > m.Before.HeapAlloc {
+		result.PeakHeapAlloc = highest - m.Before.HeapAlloc
+	}
+	result.Truncated = ctx.Err() != nil
+
+	record(result)
+	return result
 }
 
 // Example 1: Stack allocation (no heap allocation)
@@ -66,7 +420,7 @@ func stackStructAllocation() {
 		A int
 		B int
 	}
-	
+
 	sum := 0
 	for i := 0; i < 100; i++ {
 		s := SmallStruct{A: i, B: i * 2}
@@ -80,11 +434,56 @@ func largeAllocation() {
 	_ = make([]byte, 1024*1024) // 1MB
 }
 
+// Example 5: Build up a large working set, then drop it - only a sampler sees the peak
+func buildAndDropWorkingSet(ctx context.Context) {
+	const chunk, total = 64 * 1024, 8 * 1024 * 1024
+
+	var chunks [][]byte
+	for size := 0; size < total; size += chunk {
+		if ctx.Err() != nil {
+			return
+		}
+		chunks = append(chunks, make([]byte, chunk))
+		time.Sleep(100 * time.Microsecond) // give the sampler a chance to see growth
+	}
+	_ = chunks
+}
+
+// Example 6: A three-stage pipeline instrumented with checkpoints
+func checkpointedPipeline() {
+	var tracker MemTracker
+	tracker.Checkpoint("start")
+
+	// Stage 1: load - one big slice of records
+	records := make([]*LargeObject, 100)
+	for i := range records {
+		records[i] = createLargeObject(i)
+	}
+	tracker.Checkpoint("load")
+
+	// Stage 2: index - build a map from ID to record
+	index := make(map[int]*LargeObject, len(records))
+	for _, r := range records {
+		index[r.ID] = r
+	}
+	tracker.Checkpoint("index")
+
+	// Stage 3: sum - reads only, should allocate nothing
+	total := 0
+	for _, r := range records {
+		total += len(index[r.ID].Data)
+	}
+	tracker.Checkpoint("sum")
+
+	_ = total
+	tracker.Report()
+}
+
 // Demonstrate memory tracking
 func DemonstrateMemoryTracking() {
-	fmt.Println("\n" + "============================================================")
-	fmt.Println("MEMORY ALLOCATION TRACKING")
-	fmt.Println("============================================================")
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "MEMORY ALLOCATION TRACKING")
+	fmt.Fprintln(out, "============================================================")
 
 	// Track stack-only allocation
 	TrackMemory("Stack Only (should be minimal)", func() {
@@ -106,5 +505,30 @@ func DemonstrateMemoryTracking() {
 		largeAllocation()
 	})
 
-	fmt.Println("\n" + "============================================================")
+	// Repeat a measurement to see how much a single shot can be trusted
+	TrackMemoryN("Heap Allocation (createLargeObject x10, repeated)", 25, func() {
+		heapAllocationViaPointer()
+	})
+
+	// Track a constructor and keep what it built
+	obj, objResult := MeasureResult("createLargeObject(1) via MeasureResult", func() *LargeObject {
+		return createLargeObject(1)
+	})
+	fmt.Fprintf(out, "  Kept the result: ID=%d, len(Data)=%d (%d bytes in %d mallocs)\n",
+		obj.ID, len(obj.Data), objResult.TotalAlloc, objResult.Mallocs)
+
+	// Track the peak of a transient working set with the sampling measurement
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	peak := MeasureMemoryCtx(ctx, "Peak Heap (8MB working set, then dropped)", buildAndDropWorkingSet)
+	fmt.Fprintf(out, "\n=== Memory Tracking: %s ===\n", peak.Name)
+	fmt.Fprintf(out, "  Total allocated:     %d bytes\n", peak.TotalAlloc)
+	fmt.Fprintf(out, "  Peak heap growth:    %d bytes\n", peak.PeakHeapAlloc)
+	fmt.Fprintf(out, "  Elapsed:             %v\n", peak.Duration)
+	fmt.Fprintf(out, "  Truncated:           %t\n", peak.Truncated)
+
+	// Track a multi-stage workflow with named checkpoints
+	checkpointedPipeline()
+
+	fmt.Fprintln(out, "\n"+"============================================================")
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// This file reads the counters MemStats snapshots hold from either
+// runtime/metrics or runtime.ReadMemStats, and dumps every runtime metric
+// for the metrics subcommand.
+//
+// The two sources differ in one way that matters here. ReadMemStats stops
+// the world and flushes every P's allocation cache first, so a delta counts
+// each small allocation. runtime/metrics never stops the world; it credits
+// small allocations a whole span at a time, as each cached span is swapped
+// out - ten 64-byte allocations can read as 0 or as 128 bytes. Hence
+// memstats stays the default, keeping TrackMemory's numbers exact, and
+// metrics is opt-in with -stats=metrics.
+
+// memSnapshot is the part of runtime.MemStats the playground reports,
+// under the same field names, so deltas read the same from either source
+type memSnapshot struct {
+	TotalAlloc   uint64
+	HeapAlloc    uint64
+	HeapObjects  uint64
+	Mallocs      uint64
+	Frees        uint64
+	NumGC        uint32
+	HeapInuse    uint64
+	HeapIdle     uint64
+	HeapSys      uint64
+	HeapReleased uint64
+	StackInuse   uint64
+	StackSys     uint64
+	Sys          uint64
+}
+
+// statsSources maps each -stats value to the function filling a snapshot
+var statsSources = map[string]func(*memSnapshot){
+	"memstats": readMemStatsSnapshot,
+	"metrics":  readMetricsSnapshot,
+}
+
+// readSnapshot fills s from the source -stats selected
+var readSnapshot = readMemStatsSnapshot
+
+// setStatsSource selects the -stats source by name
+func setStatsSource(name string) error {
+	read, ok := statsSources[name]
+	if !ok {
+		return fmt.Errorf("unknown stats source %q (supported: memstats, metrics)", name)
+	}
+	readSnapshot = read
+	return nil
+}
+
+// readMemStatsSnapshot fills s from a stop-the-world runtime.ReadMemStats
+func readMemStatsSnapshot(s *memSnapshot) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	*s = memSnapshot{
+		TotalAlloc:   m.TotalAlloc,
+		HeapAlloc:    m.HeapAlloc,
+		HeapObjects:  m.HeapObjects,
+		Mallocs:      m.Mallocs,
+		Frees:        m.Frees,
+		NumGC:        m.NumGC,
+		HeapInuse:    m.HeapInuse,
+		HeapIdle:     m.HeapIdle,
+		HeapSys:      m.HeapSys,
+		HeapReleased: m.HeapReleased,
+		StackInuse:   m.StackInuse,
+		StackSys:     m.StackSys,
+		Sys:          m.Sys,
+	}
+}
+
+// The runtime/metrics behind each memSnapshot field. MemStats counts tiny
+// allocations (several tiny objects packed into one block) individually in
+// Mallocs and Frees; runtime/metrics reports them separately.
+const (
+	metricAllocBytes   = "/gc/heap/allocs:bytes"
+	metricAllocObjects = "/gc/heap/allocs:objects"
+	metricFreeObjects  = "/gc/heap/frees:objects"
+	metricTinyAllocs   = "/gc/heap/tiny/allocs:objects"
+	metricLiveObjects  = "/gc/heap/objects:objects"
+	metricHeapUnused   = "/memory/classes/heap/unused:bytes"
+	metricHeapFree     = "/memory/classes/heap/free:bytes"
+	metricHeapReleased = "/memory/classes/heap/released:bytes"
+	metricOSStacks     = "/memory/classes/os-stacks:bytes"
+	metricTotalMemory  = "/memory/classes/total:bytes"
+)
+
+// snapshotSamples is reused by readMetricsSnapshot, so reading costs no
+// allocation once the first call has sized it; snapshotMu guards it, as
+// the peak and -timeline samplers read from their own goroutines
+var (
+	snapshotMu      sync.Mutex
+	snapshotSamples = []metrics.Sample{
+		{Name: metricAllocBytes},
+		{Name: metricHeapObjects},
+		{Name: metricLiveObjects},
+		{Name: metricAllocObjects},
+		{Name: metricFreeObjects},
+		{Name: metricTinyAllocs},
+		{Name: metricGCCycles},
+		{Name: metricHeapUnused},
+		{Name: metricHeapFree},
+		{Name: metricHeapReleased},
+		{Name: metricHeapStacks},
+		{Name: metricOSStacks},
+		{Name: metricTotalMemory},
+	}
+)
+
+// readMetricsSnapshot fills s from runtime/metrics, without stopping the
+// world. HeapInuse is the in-use spans, objects plus their unused tails;
+// stack spans are left out of it and of HeapSys, as in MemStats.
+func readMetricsSnapshot(s *memSnapshot) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	metrics.Read(snapshotSamples)
+	v := func(i int) uint64 { return snapshotSamples[i].Value.Uint64() }
+	heapInuse, heapIdle := v(1)+v(7), v(8)+v(9)
+	*s = memSnapshot{
+		TotalAlloc:   v(0),
+		HeapAlloc:    v(1),
+		HeapObjects:  v(2),
+		Mallocs:      v(3) + v(5),
+		Frees:        v(4) + v(5),
+		NumGC:        uint32(v(6)),
+		HeapInuse:    heapInuse,
+		HeapIdle:     heapIdle,
+		HeapSys:      heapInuse + heapIdle,
+		HeapReleased: v(9),
+		StackInuse:   v(10),
+		StackSys:     v(10) + v(11),
+		Sys:          v(12),
+	}
+}
+
+// dumpMetrics prints every metric this runtime supports with its current
+// value and the first sentence of its description
+func dumpMetrics(w io.Writer) error {
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i, d := range descs {
+		samples[i].Name = d.Name
+	}
+	metrics.Read(samples)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Metric\tValue\tMeaning")
+	for i, d := range descs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Name, metricValue(samples[i].Value, d.Name), firstSentence(d.Description))
+	}
+	return tw.Flush()
+}
+
+// metricValue renders a sample: bytes with a unit, histograms as their
+// total count
+func metricValue(v metrics.Value, name string) string {
+	switch v.Kind() {
+	case metrics.KindUint64:
+		if strings.HasSuffix(name, ":bytes") {
+			return formatBytes(v.Uint64())
+		}
+		return fmt.Sprint(v.Uint64())
+	case metrics.KindFloat64:
+		return fmt.Sprintf("%.6g", v.Float64())
+	case metrics.KindFloat64Histogram:
+		var total uint64
+		for _, c := range v.Float64Histogram().Counts {
+			total += c
+		}
+		return fmt.Sprintf("histogram, %d samples", total)
+	}
+	return "unsupported"
+}
+
+// firstSentence trims a metric description to one line
+func firstSentence(desc string) string {
+	desc = strings.Join(strings.Fields(desc), " ")
+	if i := strings.Index(desc, ". "); i >= 0 {
+		return desc[:i+1]
+	}
+	return desc
+}
@@ -0,0 +1,36 @@
+package main
+
+import "golang-playground/schema"
+
+// This file collects the facts demonstrations report besides MemResults -
+// allocs/op counts, addresses shared or not - so -format=json carries them
+
+// Observation is one named value a demonstration measured or derived
+type Observation = schema.Observation
+
+// observed accumulates every Observation in the order it was made
+var observed []Observation
+
+// observe records a value under the running demonstration's name
+func observe(name string, value float64, unit string) {
+	observed = append(observed, Observation{Demo: currentDemo, Name: name, Value: value, Unit: unit})
+}
+
+// MeasureAllocs is AllocsPerRun that also records the count as an observation
+func MeasureAllocs(name string, fn func()) float64 {
+	allocs := AllocsPerRun(fn)
+	observe(name, allocs, "allocs/op")
+	return allocs
+}
+
+// DemoRecord summarizes one demonstration run for the JSON report
+type DemoRecord = schema.Demo
+
+// demoRecords converts the scoreboard into report entries
+func demoRecords() []DemoRecord {
+	records := make([]DemoRecord, 0, len(scoreboard))
+	for _, s := range scoreboard {
+		records = append(records, DemoRecord{Name: s.name, DurationNs: s.elapsed.Nanoseconds(), Measurements: s.measured})
+	}
+	return records
+}
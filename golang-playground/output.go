@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"golang-playground/schema"
+)
+
+// This file turns collected MemResults into text, JSON, CSV or Markdown
+
+// out receives the demonstrations' narrative output. Machine-readable
+// formats move it to stderr so stdout carries only the results.
+var out io.Writer = os.Stdout
+
+// ResultWriter renders a set of measurements in one output format
+type ResultWriter interface {
+	Write(results []MemResult) error
+}
+
+// resultFormats maps each -format value to its writer constructor
+var resultFormats = map[string]func(w io.Writer) ResultWriter{
+	"text":     func(w io.Writer) ResultWriter { return textWriter{w} },
+	"json":     func(w io.Writer) ResultWriter { return jsonWriter{w} },
+	"csv":      func(w io.Writer) ResultWriter { return csvWriter{w} },
+	"markdown": func(w io.Writer) ResultWriter { return markdownWriter{w} },
+}
+
+// supportedFormats lists the -format values in a stable order
+func supportedFormats() []string {
+	return []string{"text", "json", "csv", "markdown"}
+}
+
+// newResultWriter returns the writer for format, writing to w
+func newResultWriter(format string, w io.Writer) (ResultWriter, error) {
+	newWriter, ok := resultFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (supported: %s)", format, strings.Join(supportedFormats(), ", "))
+	}
+	return newWriter(w), nil
+}
+
+// textWriter prints a summary table of every measurement
+type textWriter struct{ w io.Writer }
+
+func (t textWriter) Write(results []MemResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	fmt.Fprintln(t.w, "\n"+"============================================================")
+	fmt.Fprintln(t.w, "RESULTS SUMMARY")
+	fmt.Fprintln(t.w, "============================================================")
+
+	tw := tabwriter.NewWriter(t.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Name\tTotal bytes\tHeap bytes\tObjects\tMallocs\tElapsed")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%v\n", r.Name, r.TotalAlloc, r.HeapAlloc, r.HeapObjects, r.Mallocs, r.Duration)
+	}
+	return tw.Flush()
+}
+
+// reportSchemaVersion identifies the JSON layout of Report and MemResult;
+// the history lives with the schema package
+const reportSchemaVersion = schema.Version
+
+// Report is the top-level JSON document, versioned so that consumers
+// (including the Rust comparison harness) can reject formats they don't know
+type Report = schema.Report
+
+// newReport wraps results with the current schema and Go versions, plus
+// the demos and observations of this run
+func newReport(results []MemResult) Report {
+	if results == nil {
+		results = []MemResult{} // encode as [] rather than null
+	}
+	return Report{
+		SchemaVersion: reportSchemaVersion,
+		GoVersion:     runtime.Version(),
+		Results:       results,
+		Demos:         demoRecords(),
+		Observations:  observed,
+	}
+}
+
+// jsonWriter writes the measurements as an indented JSON Report
+type jsonWriter struct{ w io.Writer }
+
+func (j jsonWriter) Write(results []MemResult) error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newReport(results))
+}
+
+// csvWriter writes one CSV row per measurement, with a header row
+type csvWriter struct{ w io.Writer }
+
+func (c csvWriter) Write(results []MemResult) error {
+	cw := csv.NewWriter(c.w)
+	cw.Write([]string{"name", "total_alloc", "heap_alloc", "heap_objects", "mallocs", "duration_ns", "peak_heap_alloc", "truncated", "frees", "gc_cycles"})
+	for _, r := range results {
+		cw.Write([]string{
+			r.Name,
+			strconv.FormatUint(r.TotalAlloc, 10),
+			strconv.FormatUint(r.HeapAlloc, 10),
+			strconv.FormatUint(r.HeapObjects, 10),
+			strconv.FormatUint(r.Mallocs, 10),
+			strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+			strconv.FormatUint(r.PeakHeapAlloc, 10),
+			strconv.FormatBool(r.Truncated),
+			strconv.FormatUint(r.Frees, 10),
+			strconv.FormatUint(uint64(r.GCCycles), 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownWriter writes a GitHub-flavored Markdown table, padded so the
+// source reads as a table too
+type markdownWriter struct{ w io.Writer }
+
+func (m markdownWriter) Write(results []MemResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	rows := [][]string{{"Name", "Total", "Heap", "Objects", "Mallocs", "Elapsed"}}
+	for _, r := range results {
+		rows = append(rows, []string{
+			strings.ReplaceAll(r.Name, "|", `\|`),
+			formatBytes(r.TotalAlloc),
+			formatBytes(r.HeapAlloc),
+			strconv.FormatUint(r.HeapObjects, 10),
+			strconv.FormatUint(r.Mallocs, 10),
+			r.Duration.String(),
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell), 3)
+		}
+	}
+
+	// Name is left-aligned, every measurement column right-aligned
+	pad := func(cell string, i int) string {
+		gap := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		if i == 0 {
+			return cell + gap
+		}
+		return gap + cell
+	}
+	separator := make([]string, len(widths))
+	for i, width := range widths {
+		if i == 0 {
+			separator[i] = strings.Repeat("-", width)
+		} else {
+			separator[i] = strings.Repeat("-", width-1) + ":"
+		}
+	}
+
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = pad(cell, j)
+		}
+		if _, err := fmt.Fprintf(m.w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+		if i == 0 {
+			if _, err := fmt.Fprintf(m.w, "| %s |\n", strings.Join(separator, " | ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. 1536 -> "1.5 KB"
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGTP"[exp])
+}
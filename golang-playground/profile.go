@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+// This file writes a heap profile on each side of every TrackMemory call,
+// so go tool pprof can show which call sites did the allocating
+
+// profileDir is where -profile-dir puts the profiles; empty disables them
+var profileDir string
+
+// profileErr is the first profile that couldn't be written; TrackMemory
+// has no error to return, so run reports it once the demos are done
+var profileErr error
+
+// enableProfiles turns on heap profiling into dir. It samples every
+// allocation rather than one per 512 KB, which only takes effect for
+// allocations made after it - so call it before any demo runs.
+func enableProfiles(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("profile dir: %w", err)
+	}
+	runtime.MemProfileRate = 1
+	profileDir = dir
+	return nil
+}
+
+// profilePath is the directory for one tracked function: one per demo,
+// then one per measurement name within it
+func profilePath(demo, name string) string {
+	if demo == "" {
+		demo = "no-demo"
+	}
+	return filepath.Join(profileDir, pathSegment(demo), pathSegment(name))
+}
+
+// pathSegment turns a measurement name like "Heap Allocation (x10)" into
+// a portable directory name like "heap-allocation-x10"
+func pathSegment(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '_' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	segment := strings.TrimRight(b.String(), "-")
+	if segment == "" {
+		return "unnamed"
+	}
+	return segment
+}
+
+// writeHeapProfile writes dir/<phase>.pprof. A heap profile describes the
+// heap as of the last completed collection, so it collects first.
+func writeHeapProfile(dir, phase string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, phase+".pprof"))
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// noteProfileErr keeps the first profiling failure for run to report
+func noteProfileErr(name string, err error) {
+	if err != nil && profileErr == nil {
+		profileErr = fmt.Errorf("heap profile for %s: %w", name, err)
+	}
+}
+
+// profiled runs measure between a "before" and an "after" heap profile
+// when -profile-dir is set, and returns the profiles' directory ("" if off).
+// The profiles are written outside measure, so they don't count against it.
+func profiled(name string, measure func()) string {
+	if profileDir == "" {
+		measure()
+		return ""
+	}
+	dir := profilePath(currentDemo, name)
+	noteProfileErr(name, writeHeapProfile(dir, "before"))
+	measure()
+	noteProfileErr(name, writeHeapProfile(dir, "after"))
+	return dir
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// quizQuestion pairs a snippet with the function that really runs it, so
+// the answer comes from DidAllocate rather than from a hard-coded key
+type quizQuestion struct {
+	snippet string
+	fn      func()
+	why     string
+}
+
+var quizQuestions = []quizQuestion{
+	{
+		snippet: "x := 42\n_ = x",
+		fn:      noEscape,
+		why:     "x is never referenced outside the frame.",
+	},
+	{
+		snippet: "func escapesViaReturn() *int { x := 42; return &x }\n\np := escapesViaReturn()\n_ = *p",
+		fn:      func() { _ = *escapesViaReturn() },
+		why:     "escapesViaReturn is inlined, so the caller sees the pointer never leaves.",
+	},
+	{
+		snippet: "var globalInterface interface{}\n\nx := 42\nglobalInterface = x",
+		fn:      escapesViaInterface,
+		why:     "Boxing does escape x - but ints 0..255 point into a static table, no malloc.",
+	},
+	{
+		snippet: "var globalPtr *int\n\nx := 42\nglobalPtr = &x",
+		fn:      escapesViaGlobal,
+		why:     "A global can be read at any time later, so x must outlive the frame.",
+	},
+	{
+		snippet: "x := fmtInput * 1000\nfmt.Fprintln(io.Discard, x)",
+		fn:      escapesViaFmt,
+		why:     "fmt takes ...interface{}: x is boxed and the box escapes into reflection.",
+	},
+	{
+		snippet: "u := User{Name: \"Carol\", Age: 40}\nchannelSink = u.Age",
+		fn:      noEscapeWithoutChannel,
+		why:     "Only an int is copied out; the User stays in the frame.",
+	},
+	{
+		snippet: "ch := make(chan *User, 1)\nch <- &User{Name: \"Carol\", Age: 40}\nchannelSink = (<-ch).Age",
+		fn:      escapesViaChannel,
+		why:     "Channels are always heap-allocated, and a sent pointer escapes with them.",
+	},
+	{
+		snippet: "users := make([]*User, 0, 8)\nfor i := 0; i < 8; i++ {\n\tu := User{Name: \"user\", Age: i}\n\tusers = append(users, &u)\n}\nuserPtrSliceSink = users",
+		fn:      escapesViaSliceOfPointers,
+		why:     "Each &u is stored in a slice that outlives the loop: moved to heap: u.",
+	},
+}
+
+// runQuiz asks whether each snippet allocates, reading y/n answers from in,
+// and prints the measured answer and a final score to w. Running out of
+// input ends the quiz early and still reports the score.
+func runQuiz(in io.Reader, w io.Writer) error {
+	fmt.Fprintln(w, "MEMORY MODEL QUIZ")
+	fmt.Fprintln(w, "For each snippet, answer y if it heap-allocates, n if it doesn't.")
+
+	scanner := bufio.NewScanner(in)
+	score, asked := 0, 0
+	for i, q := range quizQuestions {
+		fmt.Fprintf(w, "\n--- Question %d/%d ---\n", i+1, len(quizQuestions))
+		for _, line := range strings.Split(q.snippet, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+
+		guess, ok, err := askYesNo(scanner, w, "Does this allocate on the heap? [y/n] ")
+		if err != nil {
+			return fmt.Errorf("read answer: %w", err)
+		}
+		if !ok {
+			fmt.Fprintln(w, "\n(no more input)")
+			break
+		}
+
+		asked++
+		allocates := DidAllocate(q.fn)
+		if guess == allocates {
+			score++
+			fmt.Fprint(w, "  Correct! ")
+		} else {
+			fmt.Fprint(w, "  Not quite. ")
+		}
+		fmt.Fprintf(w, "Measured: %.0f allocs/op. %s\n", AllocsPerRun(q.fn), q.why)
+	}
+
+	fmt.Fprintf(w, "\nScore: %d/%d\n", score, asked)
+	return nil
+}
+
+// askYesNo prompts until it reads a y/n answer; ok is false at end of input
+func askYesNo(scanner *bufio.Scanner, w io.Writer, prompt string) (answer, ok bool, err error) {
+	for {
+		fmt.Fprint(w, prompt)
+		if !scanner.Scan() {
+			return false, false, scanner.Err()
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "y", "yes":
+			return true, true, nil
+		case "n", "no":
+			return false, true, nil
+		}
+		fmt.Fprintln(w, "  Please answer y or n.")
+	}
+}
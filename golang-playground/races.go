@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// This file shows the data races Rust's borrow checker rejects at compile
+// time and Go only catches at run time, if at all: with -race, or when the
+// runtime happens to notice. Each racy version has a race-free twin.
+
+func init() {
+	Register(newDemo("data-races", "Racy counter, map and lazy init next to their fixes (-unsafe-races)", DemonstrateDataRaces).in(categoryMemoryModel))
+}
+
+// unsafeRaces enables the racy versions; -unsafe-races sets it
+var unsafeRaces bool
+
+// Every racy example runs raceGoroutines goroutines of raceIterations steps
+const (
+	raceGoroutines = 4
+	raceIterations = 100_000
+)
+
+// parallel runs fn(g) on raceGoroutines goroutines and waits for all of them
+func parallel(fn func(g int)) {
+	var wg sync.WaitGroup
+	for g := 0; g < raceGoroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(g)
+		}()
+	}
+	wg.Wait()
+}
+
+// racyCounter increments a plain int from every goroutine: counter++ is a
+// load, an add and a store, and two goroutines can interleave them
+func racyCounter() int {
+	counter := 0
+	parallel(func(int) {
+		for i := 0; i < raceIterations; i++ {
+			counter++ // DATA RACE
+		}
+	})
+	return counter
+}
+
+// atomicCounter is racyCounter with the read-modify-write made indivisible
+func atomicCounter() int64 {
+	var counter atomic.Int64
+	parallel(func(int) {
+		for i := 0; i < raceIterations; i++ {
+			counter.Add(1)
+		}
+	})
+	return counter.Load()
+}
+
+// racyMap writes a shared map from every goroutine. The runtime checks for
+// this on a best-effort basis and aborts with "concurrent map writes" -
+// an unrecoverable fatal error, not a panic.
+func racyMap() int {
+	m := make(map[int]int)
+	parallel(func(g int) {
+		for i := 0; i < raceIterations; i++ {
+			m[g*raceIterations+i] = i // DATA RACE
+		}
+	})
+	return len(m)
+}
+
+// lockedMap is racyMap with every write under one mutex
+func lockedMap() int {
+	var mu sync.Mutex
+	m := make(map[int]int)
+	parallel(func(g int) {
+		for i := 0; i < raceIterations; i++ {
+			mu.Lock()
+			m[g*raceIterations+i] = i
+			mu.Unlock()
+		}
+	})
+	return len(m)
+}
+
+// raceConfig is what the lazy-init examples build exactly once, ideally
+type raceConfig struct {
+	settings map[string]string
+}
+
+// loadRaceConfig builds a raceConfig slowly enough - it yields halfway -
+// for other goroutines to arrive while it runs, counting its calls
+func loadRaceConfig(loads *atomic.Int32) *raceConfig {
+	loads.Add(1)
+	runtime.Gosched()
+	return &raceConfig{settings: map[string]string{"mode": "demo"}}
+}
+
+// racyLazyInit is the check-then-set pattern: every goroutine that sees nil
+// before the first one stores its result builds its own config
+func racyLazyInit() int32 {
+	var (
+		loads  atomic.Int32
+		config *raceConfig
+	)
+	parallel(func(int) {
+		if config == nil { // DATA RACE: read
+			config = loadRaceConfig(&loads) // DATA RACE: write
+		}
+		_ = config.settings["mode"]
+	})
+	return loads.Load()
+}
+
+// onceLazyInit is racyLazyInit with sync.OnceValue, which runs the loader
+// once and makes its result visible to every caller
+func onceLazyInit() int32 {
+	var loads atomic.Int32
+	config := sync.OnceValue(func() *raceConfig { return loadRaceConfig(&loads) })
+	parallel(func(int) {
+		_ = config().settings["mode"]
+	})
+	return loads.Load()
+}
+
+// DemonstrateDataRaces runs the race-free versions, plus the racy ones
+// when -unsafe-races asks for them
+func DemonstrateDataRaces() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "DATA RACES: WHAT GO CATCHES AT RUN TIME")
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintf(out, "  %d goroutines x %d steps each, GOMAXPROCS=%d\n\n", raceGoroutines, raceIterations, runtime.GOMAXPROCS(0))
+
+	want := raceGoroutines * raceIterations
+	fmt.Fprintf(out, "  Counter, atomic.Int64:      %d of %d increments\n", atomicCounter(), want)
+	fmt.Fprintf(out, "  Map, sync.Mutex:            %d of %d keys\n", lockedMap(), want)
+	fmt.Fprintf(out, "  Lazy init, sync.OnceValue:  %d load(s)\n", onceLazyInit())
+
+	fmt.Fprintln(out, "\n  Each racy version below compiles without a warning; a lost update or a")
+	fmt.Fprintln(out, "  second load may or may not happen on a given run, so a passing run proves")
+	fmt.Fprintln(out, "  nothing. -race reports every one of them when it executes. Rust rejects all")
+	fmt.Fprintln(out, "  three at compile time: a shared &mut across threads doesn't pass the borrow")
+	fmt.Fprintln(out, "  checker, and the fixes (AtomicI64, Mutex<HashMap>, OnceLock) are the only")
+	fmt.Fprintln(out, "  versions that compile.")
+
+	if !unsafeRaces {
+		fmt.Fprintln(out, "\n  The racy versions are skipped. Run them with")
+		fmt.Fprintln(out, "    go run -race . -unsafe-races data-races")
+		fmt.Fprintln(out, "  (the map example may kill the process, with or without -race)")
+		return
+	}
+
+	fmt.Fprintln(out, "\n  Racy versions (-unsafe-races):")
+	counter := racyCounter()
+	fmt.Fprintf(out, "  Counter, plain int:         %d of %d increments (%d lost)\n", counter, want, want-counter)
+	observe("racy counter lost increments", float64(want-counter), "increments")
+	loads := racyLazyInit()
+	fmt.Fprintf(out, "  Lazy init, check-then-set:  %d load(s)\n", loads)
+	observe("racy lazy init loads", float64(loads), "loads")
+
+	// Last, because the runtime's map check ends the program when it fires
+	fmt.Fprintln(out, "  Map, unguarded: may abort here with \"fatal error: concurrent map writes\"")
+	fmt.Fprintf(out, "  Map, unguarded:             %d of %d keys (the runtime didn't notice)\n", racyMap(), want)
+}
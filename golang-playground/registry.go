@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Demonstration is a self-contained example the playground can run
+type Demonstration interface {
+	Name() string
+	Description() string
+	Run() error
+}
+
+// demoCategory groups demonstrations by topic. Categories run and list
+// in the order declared here, and within one category demos keep the
+// order they were registered in
+type demoCategory int
+
+const (
+	categoryNone        demoCategory = iota // not yet filed: listed last
+	categoryBasics                          // stack vs heap, sharing, tracking
+	categoryEscape                          // escape analysis
+	categoryCollections                     // slices, maps and strings
+	categoryInterfaces                      // interfaces and boxing
+	categoryLayout                          // struct layout and locality
+	categoryAllocator                       // size classes, pools, arenas
+	categoryGC                              // the garbage collector and the OS
+	categoryGoroutines                      // goroutine stacks
+	categoryMemoryModel                     // happens-before and data races
+)
+
+// rank is where a category sorts: categoryNone goes after every other
+func (c demoCategory) rank() int {
+	if c == categoryNone {
+		return int(categoryMemoryModel) + 1
+	}
+	return int(c)
+}
+
+// demoRank is a demonstration's category rank: one that doesn't report a
+// category sorts with categoryNone
+func demoRank(d Demonstration) int {
+	if c, ok := d.(interface{ Category() demoCategory }); ok {
+		return c.Category().rank()
+	}
+	return categoryNone.rank()
+}
+
+// registry holds every demonstration, ordered by category
+var registry []Demonstration
+
+// Register adds a demonstration to the registry after every demo of its
+// category registered so far - call it from init()
+func Register(d Demonstration) {
+	rank := demoRank(d)
+	i := slices.IndexFunc(registry, func(r Demonstration) bool { return demoRank(r) > rank })
+	if i < 0 {
+		i = len(registry)
+	}
+	registry = slices.Insert(registry, i, d)
+}
+
+// demo is the Demonstration used by the examples in this package
+type demo struct {
+	name        string
+	description string
+	category    demoCategory
+	run         func() error
+}
+
+func (d demo) Name() string           { return d.name }
+func (d demo) Description() string    { return d.description }
+func (d demo) Category() demoCategory { return d.category }
+func (d demo) Run() error             { return d.run() }
+
+// in files the demo under a category
+func (d demo) in(c demoCategory) demo {
+	d.category = c
+	return d
+}
+
+// newDemo wraps an example that cannot fail
+func newDemo(name, description string, fn func()) demo {
+	return demo{name: name, description: description, run: func() error {
+		fn()
+		return nil
+	}}
+}
+
+// demoNames lists the registered demonstration names
+func demoNames() []string {
+	names := make([]string, 0, len(registry))
+	for _, d := range registry {
+		names = append(names, d.Name())
+	}
+	return names
+}
+
+// Demos left out of a full run and run only by name: escape-verify
+// rebuilds the package from source with the go command
+var onDemandDemos = map[string]bool{
+	"escape-verify": true,
+}
+
+// listDemos prints every registered demonstration with its description
+func listDemos(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, d := range registry {
+		note := ""
+		if onDemandDemos[d.Name()] {
+			note = " (only run by name)"
+		}
+		fmt.Fprintf(tw, "%s\t%s%s\n", d.Name(), d.Description(), note)
+	}
+	return tw.Flush()
+}
+
+// defaultDemos is what a run without a demo name covers: every
+// demonstration but the onDemandDemos
+func defaultDemos() []Demonstration {
+	return slices.DeleteFunc(slices.Clone(registry), func(d Demonstration) bool {
+		return onDemandDemos[d.Name()]
+	})
+}
+
+// selectDemos returns the demonstration called name, or the defaultDemos
+// if name is empty
+func selectDemos(name string) ([]Demonstration, error) {
+	if name == "" {
+		return defaultDemos(), nil
+	}
+	d, err := findDemo(name)
+	if err != nil {
+		return nil, err
+	}
+	return []Demonstration{d}, nil
+}
+
+// findDemo returns the registered demonstration called name
+func findDemo(name string) (Demonstration, error) {
+	for _, d := range registry {
+		if d.Name() == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown demo %q (valid: %s)", name, strings.Join(demoNames(), ", "))
+}
+
+// Demos about data races - their output only proves something under -race
+var raceSensitiveDemos = map[string]bool{
+	"happens-before": true,
+	"data-races":     true,
+	"litmus":         true,
+}
+
+// warnWithoutRace tells the user a race-sensitive demo needs the detector
+func warnWithoutRace(name string) {
+	fmt.Fprintf(out, "\nWARNING: %s is about data races, but this binary was built without\n", name)
+	fmt.Fprintln(out, "the race detector - a racy program can look fine without it. Rebuild with:")
+	fmt.Fprintf(out, "  go run -race . -demo=%s\n", name)
+}
+
+// verbose announces each demonstration before runDemos starts it
+var verbose bool
+
+// runDemos runs each demonstration in order, stopping at the first failure
+func runDemos(demos []Demonstration) error {
+	defer func() { currentDemo = "" }()
+	for _, d := range demos {
+		currentDemo = d.Name()
+		logEvent("start", "demo started")
+		if verbose {
+			fmt.Fprintf(out, "\n>>> %s: %s\n", d.Name(), d.Description())
+		}
+		if !raceEnabled && raceSensitiveDemos[d.Name()] {
+			warnWithoutRace(d.Name())
+		}
+		start, began := len(recorded), time.Now()
+		err := inRegion(d.Name(), d.Run)
+		addScore(d.Name(), recorded[start:], time.Since(began))
+		if err != nil {
+			logEvent("error", "demo failed", slog.Any("error", err))
+			return fmt.Errorf("%s: %w", d.Name(), err)
+		}
+		logEvent("done", "demo finished")
+	}
+	return nil
+}
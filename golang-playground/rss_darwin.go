@@ -0,0 +1,25 @@
+//go:build darwin && cgo
+
+package main
+
+/*
+#include <mach/mach.h>
+
+static kern_return_t basic_info(mach_task_basic_info_data_t *info) {
+	mach_msg_type_number_t count = MACH_TASK_BASIC_INFO_COUNT;
+	return task_info(mach_task_self(), MACH_TASK_BASIC_INFO, (task_info_t)info, &count);
+}
+*/
+import "C"
+
+import "fmt"
+
+// ProcessMemory asks the Mach kernel for this task's resident and virtual
+// sizes through task_info
+func ProcessMemory() (ProcessMem, error) {
+	var info C.mach_task_basic_info_data_t
+	if kr := C.basic_info(&info); kr != C.KERN_SUCCESS {
+		return ProcessMem{}, fmt.Errorf("task_info: kern_return_t %d", int(kr))
+	}
+	return ProcessMem{RSS: uint64(info.resident_size), VMS: uint64(info.virtual_size)}, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProcessMemory reads VmRSS and VmSize from /proc/self/status, which the
+// kernel reports in kB
+func ProcessMemory() (ProcessMem, error) {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return ProcessMem{}, fmt.Errorf("read status: %w", err)
+	}
+	var mem ProcessMem
+	fields := map[string]*uint64{"VmRSS:": &mem.RSS, "VmSize:": &mem.VMS}
+	found := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.Fields(scanner.Text())
+		if len(line) < 2 || fields[line[0]] == nil {
+			continue
+		}
+		kb, err := strconv.ParseUint(line[1], 10, 64)
+		if err != nil {
+			return ProcessMem{}, fmt.Errorf("parse status %s: %w", line[0], err)
+		}
+		*fields[line[0]] = kb << 10
+		found++
+	}
+	if found < len(fields) {
+		return ProcessMem{}, fmt.Errorf("parse status: VmRSS or VmSize missing")
+	}
+	return mem, nil
+}
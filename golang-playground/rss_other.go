@@ -0,0 +1,13 @@
+//go:build !linux && !windows && !(darwin && cgo)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// ProcessMemory is implemented for Linux, Windows and (with cgo) macOS
+func ProcessMemory() (ProcessMem, error) {
+	return ProcessMem{}, fmt.Errorf("process memory is not implemented on %s", runtime.GOOS)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// processMemoryCounters is PROCESS_MEMORY_COUNTERS from psapi.h
+type processMemoryCounters struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+}
+
+// kernel32 exports GetProcessMemoryInfo as K32GetProcessMemoryInfo since
+// Windows 7, which saves loading psapi.dll
+var procGetProcessMemoryInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// ProcessMemory reads the working set (RSS) and the committed private bytes
+// (the closest Windows has to VMS) with GetProcessMemoryInfo
+func ProcessMemory() (ProcessMem, error) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return ProcessMem{}, fmt.Errorf("GetCurrentProcess: %w", err)
+	}
+	var counters processMemoryCounters
+	counters.cb = uint32(unsafe.Sizeof(counters))
+	ok, _, err := procGetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb))
+	if ok == 0 {
+		return ProcessMem{}, fmt.Errorf("GetProcessMemoryInfo: %w", err)
+	}
+	return ProcessMem{RSS: uint64(counters.workingSetSize), VMS: uint64(counters.pagefileUsage)}, nil
+}
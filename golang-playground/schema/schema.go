@@ -0,0 +1,90 @@
+// Package schema defines the machine-readable results document the Go
+// playground writes (-format=json) and the compare command reads.
+//
+// It is the contract between the two playgrounds: any producer - the Rust
+// playground included - that writes a Report with these JSON field names can
+// be compared against the Go results. Byte and allocation counts are deltas
+// measured around one named scenario; scenarios are matched by Name.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Version identifies the JSON layout of Report and Result.
+// Bump it whenever a field is added, removed, renamed or changes meaning.
+//
+//	1: initial layout
+//	2: Result gains frees and gc_cycles
+//	3: Report gains demos and observations
+const Version = 3
+
+// Report is the top-level JSON document, versioned so that consumers
+// can reject formats they don't know
+type Report struct {
+	SchemaVersion int      `json:"schema_version"`
+	GoVersion     string   `json:"go_version"` // empty when the producer isn't Go
+	Results       []Result `json:"results"`
+
+	// Every demonstration that ran, and the values they reported outside
+	// of Results (allocs/op and similar); both empty in older reports
+	Demos        []Demo        `json:"demos,omitempty"`
+	Observations []Observation `json:"observations,omitempty"`
+}
+
+// Result holds the allocation deltas measured around a single scenario
+type Result struct {
+	Name        string `json:"name"`
+	TotalAlloc  uint64 `json:"total_alloc"`
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapObjects uint64 `json:"heap_objects"`
+	Mallocs     uint64 `json:"mallocs"`
+	Frees       uint64 `json:"frees"`
+	GCCycles    uint32 `json:"gc_cycles"` // collections that completed while it ran
+
+	// Wall-clock time of the scenario alone, in nanoseconds
+	Duration time.Duration `json:"duration_ns"`
+
+	// Set only by sampling measurements, which track the peak as they go
+	PeakHeapAlloc uint64 `json:"peak_heap_alloc,omitempty"`
+	Truncated     bool   `json:"truncated,omitempty"`
+}
+
+// Demo summarizes one demonstration run
+type Demo struct {
+	Name         string `json:"name"`
+	DurationNs   int64  `json:"duration_ns"`
+	Measurements int    `json:"measurements"`
+}
+
+// Observation is one named value a demonstration measured or derived
+type Observation struct {
+	Demo  string  `json:"demo"`
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// Decode parses either a versioned Report or a bare JSON array of Result,
+// which it wraps in a Report. Older schema versions only lack fields, which
+// decode as zero; reports from a newer schema are rejected.
+func Decode(data []byte) (Report, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var results []Result
+		err := json.Unmarshal(data, &results)
+		return Report{Results: results}, err
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return Report{}, err
+	}
+	if report.SchemaVersion < 1 || report.SchemaVersion > Version {
+		return Report{}, fmt.Errorf("schema version %d, want 1 to %d", report.SchemaVersion, Version)
+	}
+	return report, nil
+}
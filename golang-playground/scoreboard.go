@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// This file ranks the demonstrations of a run by what they cost
+
+// demoScore totals the measurements one demonstration recorded
+type demoScore struct {
+	name        string
+	totalAlloc  uint64
+	heapObjects uint64
+	measured    int           // how many MemResults the demo recorded
+	elapsed     time.Duration // wall time of the whole demo, output included
+}
+
+// scoreboard gets one entry per demonstration runDemos executes
+var scoreboard []demoScore
+
+// addScore totals results, the MemResults recorded while demo name ran
+func addScore(name string, results []MemResult, elapsed time.Duration) {
+	score := demoScore{name: name, measured: len(results), elapsed: elapsed}
+	for _, r := range results {
+		score.totalAlloc += r.TotalAlloc
+		score.heapObjects += r.HeapObjects
+	}
+	scoreboard = append(scoreboard, score)
+}
+
+// printScoreboard ranks the demos by total bytes allocated and names the
+// heaviest by bytes, objects and duration
+func printScoreboard() {
+	if len(scoreboard) == 0 {
+		return
+	}
+	ranked := slices.Clone(scoreboard)
+	slices.SortStableFunc(ranked, func(a, b demoScore) int {
+		return cmp.Compare(b.totalAlloc, a.totalAlloc)
+	})
+	mostObjects := slices.MaxFunc(ranked, func(a, b demoScore) int { return cmp.Compare(a.heapObjects, b.heapObjects) })
+	longest := slices.MaxFunc(ranked, func(a, b demoScore) int { return cmp.Compare(a.elapsed, b.elapsed) })
+
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "SCOREBOARD")
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintf(out, "  Most heap:    %s (%s)\n", ranked[0].name, formatBytes(ranked[0].totalAlloc))
+	fmt.Fprintf(out, "  Most objects: %s (%d)\n", mostObjects.name, mostObjects.heapObjects)
+	fmt.Fprintf(out, "  Longest:      %s (%v)\n\n", longest.name, longest.elapsed.Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Rank\tDemo\tTracked bytes\tObjects\tDuration")
+	rank := 0
+	for _, s := range ranked {
+		if s.measured == 0 {
+			continue
+		}
+		rank++
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%d\t%v\n", rank, s.name, formatBytes(s.totalAlloc), s.heapObjects, s.elapsed.Round(time.Microsecond))
+	}
+	tw.Flush()
+	fmt.Fprintln(out, "  (Bytes and objects sum each demo's TrackMemory measurements; demos that")
+	fmt.Fprintln(out, "  only print allocs/op are left out of the table but count for Longest.)")
+}
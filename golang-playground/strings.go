@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file compares ways of building strings - strings are immutable in Go
+
+func init() {
+	Register(newDemo("string-building", "s += in a loop vs a pre-sized strings.Builder", DemonstrateStringBuilding).in(categoryCollections))
+}
+
+const stringPieces = 10000
+
+// Keeps each final string alive so neither loop can be optimized away
+var stringSink string
+
+// Every += allocates a new string and copies everything built so far: O(n^2) bytes
+func concatWithPlus() {
+	s := ""
+	for i := 0; i < stringPieces; i++ {
+		s += "x"
+	}
+	stringSink = s
+}
+
+// Builder writes into one growable buffer; Grow sizes it once up front
+func concatWithBuilder() {
+	var b strings.Builder
+	b.Grow(stringPieces)
+	for i := 0; i < stringPieces; i++ {
+		b.WriteString("x")
+	}
+	stringSink = b.String()
+}
+
+// Demonstrate repeated concatenation against a pre-sized strings.Builder
+func DemonstrateStringBuilding() {
+	fmt.Fprintln(out, "\n"+"============================================================")
+	fmt.Fprintln(out, "STRING BUILDING: += VS strings.Builder")
+	fmt.Fprintln(out, "============================================================")
+
+	plus := TrackMemory(fmt.Sprintf("s += \"x\" (%d times)", stringPieces), func() {
+		concatWithPlus()
+	})
+	builder := TrackMemory(fmt.Sprintf("strings.Builder + Grow (%d writes)", stringPieces), func() {
+		concatWithBuilder()
+	})
+
+	fmt.Fprintf(out, "\n  Final length: %d bytes either way\n", len(stringSink))
+	if builder.TotalAlloc > 0 && builder.Mallocs > 0 {
+		fmt.Fprintf(out, "  += allocated %dx the bytes with %dx the mallocs\n",
+			plus.TotalAlloc/builder.TotalAlloc, plus.Mallocs/builder.Mallocs)
+	}
+	fmt.Fprintln(out, "  Go strings are immutable, so each += copies the whole string so far.")
+	fmt.Fprintln(out, "  The Rust analog: String::push_str into a String::with_capacity buffer,")
+	fmt.Fprintln(out, "  versus building a fresh String with format! on every iteration.")
+	stringSink = ""
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+)
+
+// This file records HeapAlloc over time so GC sawtooth patterns can be plotted
+
+// heapSample is one point of the timeline
+type heapSample struct {
+	elapsed   time.Duration
+	heapAlloc uint64
+}
+
+// Samples preallocated up front, so the sampler rarely allocates while
+// the demos it observes are being measured
+const timelineInitialSamples = 16 * 1024
+
+// heapTimeline samples HeapAlloc on a background goroutine until stopped
+type heapTimeline struct {
+	stop    chan struct{}
+	done    chan struct{}
+	samples []heapSample
+}
+
+// startTimeline begins sampling every interval
+func startTimeline(interval time.Duration) *heapTimeline {
+	t := &heapTimeline{
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		samples: make([]heapSample, 0, timelineInitialSamples),
+	}
+	go t.sample(interval)
+	return t
+}
+
+func (t *heapTimeline) sample(interval time.Duration) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	var m memSnapshot
+	for {
+		readSnapshot(&m)
+		t.samples = append(t.samples, heapSample{elapsed: time.Since(start), heapAlloc: m.HeapAlloc})
+		select {
+		case <-ticker.C:
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// Stop ends sampling, waits for the sampler to exit and returns the samples
+func (t *heapTimeline) Stop() []heapSample {
+	close(t.stop)
+	<-t.done
+	return t.samples
+}
+
+// writeTimeline writes samples to path as timestamp_ms,heap_alloc_bytes rows
+func writeTimeline(path string, samples []heapSample) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create timeline: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "timestamp_ms,heap_alloc_bytes")
+	for _, s := range samples {
+		fmt.Fprintf(w, "%.3f,%d\n", float64(s.elapsed.Microseconds())/1000, s.heapAlloc)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("write timeline: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write timeline: %w", err)
+	}
+	return nil
+}
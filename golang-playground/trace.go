@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/trace"
+)
+
+// This file records an execution trace for -trace, with one region per
+// demonstration so go tool trace can pick out each example's GC assists,
+// scheduling and heap growth
+
+// traceCtx carries the task every demo region belongs to while tracing
+var traceCtx = context.Background()
+
+// startTrace starts writing an execution trace to path and returns the
+// function that stops it and closes the file
+func startTrace(path string) (stop func() error, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("trace: %w", err)
+	}
+	if err := trace.Start(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("trace: %w", err)
+	}
+	ctx, task := trace.NewTask(context.Background(), "demos")
+	traceCtx = ctx
+	return func() error {
+		task.End()
+		traceCtx = context.Background()
+		trace.Stop()
+		return f.Close()
+	}, nil
+}
+
+// inRegion runs fn as a trace region named after the demonstration; it
+// costs next to nothing when no trace is being recorded
+func inRegion(name string, fn func() error) error {
+	var err error
+	trace.WithRegion(traceCtx, name, func() { err = fn() })
+	return err
+}